@@ -0,0 +1,312 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/neet-007/lox_lsp_first/pkg/rpc"
+)
+
+// Client plays the editor's side of a session. It frames messages with
+// rpc.EncodeMessage and reads replies with rpc.Split, so a server can be
+// driven over in-memory pipes.
+type Client struct {
+	writer        io.Writer
+	messages      chan []byte
+	notifications []clientMessage
+	nextId        int
+	Timeout       time.Duration
+}
+
+type clientRequest struct {
+	RPC    string `json:"jsonrpc"`
+	Id     int    `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params"`
+}
+
+type clientNotification struct {
+	RPC    string `json:"jsonrpc"`
+	Method string `json:"method"`
+	Params any    `json:"params"`
+}
+
+type clientMessage struct {
+	Id     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func NewClient(reader io.Reader, writer io.Writer) *Client {
+	client := &Client{
+		writer:        writer,
+		messages:      make(chan []byte, 64),
+		notifications: []clientMessage{},
+		nextId:        1,
+		Timeout:       5 * time.Second,
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		scanner.Split(rpc.Split)
+		for scanner.Scan() {
+			_, content, err := rpc.DecodeMessage(scanner.Bytes())
+			if err != nil {
+				continue
+			}
+			client.messages <- bytes.Clone(content)
+		}
+		close(client.messages)
+	}()
+
+	return client
+}
+
+func (client *Client) Notify(method string, params any) error {
+	_, err := client.writer.Write([]byte(rpc.EncodeMessage(clientNotification{
+		RPC:    "2.0",
+		Method: method,
+		Params: params,
+	})))
+	return err
+}
+
+// Request sends a request and waits for its response, keeping the
+// notifications that arrive in the meantime for AwaitNotification.
+func (client *Client) Request(method string, params any, result any) error {
+	id := client.nextId
+	client.nextId++
+
+	_, err := client.writer.Write([]byte(rpc.EncodeMessage(clientRequest{
+		RPC:    "2.0",
+		Id:     id,
+		Method: method,
+		Params: params,
+	})))
+	if err != nil {
+		return err
+	}
+
+	for {
+		message, err := client.next()
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+
+		if message.Method != "" || message.Id == nil || *message.Id != id {
+			client.notifications = append(client.notifications, message)
+			continue
+		}
+
+		if message.Error != nil {
+			return fmt.Errorf("%s: code %d: %s", method, message.Error.Code, message.Error.Message)
+		}
+
+		if result == nil {
+			return nil
+		}
+
+		return json.Unmarshal(message.Result, result)
+	}
+}
+
+func (client *Client) AwaitNotification(method string, params any) error {
+	for i, message := range client.notifications {
+		if message.Method == method {
+			client.notifications = append(client.notifications[:i], client.notifications[i+1:]...)
+			return json.Unmarshal(message.Params, params)
+		}
+	}
+
+	for {
+		message, err := client.next()
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+
+		if message.Method != method {
+			client.notifications = append(client.notifications, message)
+			continue
+		}
+
+		return json.Unmarshal(message.Params, params)
+	}
+}
+
+func (client *Client) next() (clientMessage, error) {
+	select {
+	case content, ok := <-client.messages:
+		if !ok {
+			return clientMessage{}, io.EOF
+		}
+
+		var message clientMessage
+		if err := json.Unmarshal(content, &message); err != nil {
+			return clientMessage{}, err
+		}
+
+		return message, nil
+	case <-time.After(client.Timeout):
+		return clientMessage{}, fmt.Errorf("no message after %s", client.Timeout)
+	}
+}
+
+func (client *Client) Initialize(trace string) (InitializeResult, error) {
+	return client.InitializeWorkspace(trace, "")
+}
+
+// InitializeWorkspace initializes the server with a workspace to index,
+// which is left out when rootURI is empty.
+func (client *Client) InitializeWorkspace(trace string, rootURI string) (InitializeResult, error) {
+	params := map[string]any{
+		"clientInfo": ClientInfo{Name: "lox_lsp test client", Version: "0.0.0"},
+		"trace":      trace,
+	}
+	if rootURI != "" {
+		params["rootUri"] = rootURI
+	}
+
+	var result InitializeResult
+	err := client.Request("initialize", params, &result)
+	return result, err
+}
+
+func (client *Client) ChangeConfiguration(settings any) error {
+	return client.Notify("workspace/didChangeConfiguration", map[string]any{
+		"settings": settings,
+	})
+}
+
+func (client *Client) SetTrace(value string) error {
+	return client.Notify("$/setTrace", SetTraceParams{Value: value})
+}
+
+func (client *Client) OpenDocument(uri string, text string) error {
+	return client.Notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:        uri,
+			LanguageID: "lox",
+			Version:    1,
+			Text:       text,
+		},
+	})
+}
+
+func (client *Client) ChangeDocument(uri string, version int, text string) error {
+	return client.Notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionTextDocumentIdentifier{
+			TextDocumentIdentifier: TextDocumentIdentifier{URI: uri},
+			Version:                version,
+		},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: text}},
+	})
+}
+
+// AwaitDiagnostics waits for the next diagnostics published for uri.
+func (client *Client) AwaitDiagnostics(uri string) ([]Diagnostic, error) {
+	for {
+		var params PublishDiagnosticsParams
+		if err := client.AwaitNotification("textDocument/publishDiagnostics", &params); err != nil {
+			return nil, err
+		}
+
+		if params.URI == uri {
+			return params.Diagnostics, nil
+		}
+	}
+}
+
+func (client *Client) Hover(uri string, position Position) (*HoverResult, error) {
+	var result *HoverResult
+	err := client.Request("textDocument/hover", positionParams(uri, position), &result)
+	return result, err
+}
+
+func (client *Client) Definition(uri string, position Position) (*Location, error) {
+	var result *Location
+	err := client.Request("textDocument/definition", positionParams(uri, position), &result)
+	return result, err
+}
+
+func (client *Client) Completion(uri string, position Position) ([]CompletionItem, error) {
+	var result []CompletionItem
+	err := client.Request("textDocument/completion", positionParams(uri, position), &result)
+	return result, err
+}
+
+func (client *Client) DocumentSymbols(uri string) ([]DocumentSymbol, error) {
+	var result []DocumentSymbol
+	err := client.Request("textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}, &result)
+	return result, err
+}
+
+func (client *Client) PrepareCallHierarchy(uri string, position Position) ([]CallHierarchyItem, error) {
+	var result []CallHierarchyItem
+	err := client.Request("textDocument/prepareCallHierarchy", positionParams(uri, position), &result)
+	return result, err
+}
+
+func (client *Client) IncomingCalls(item CallHierarchyItem) ([]CallHierarchyIncomingCall, error) {
+	var result []CallHierarchyIncomingCall
+	err := client.Request("callHierarchy/incomingCalls", CallHierarchyCallsParams{Item: item}, &result)
+	return result, err
+}
+
+func (client *Client) OutgoingCalls(item CallHierarchyItem) ([]CallHierarchyOutgoingCall, error) {
+	var result []CallHierarchyOutgoingCall
+	err := client.Request("callHierarchy/outgoingCalls", CallHierarchyCallsParams{Item: item}, &result)
+	return result, err
+}
+
+func (client *Client) PrepareTypeHierarchy(uri string, position Position) ([]TypeHierarchyItem, error) {
+	var result []TypeHierarchyItem
+	err := client.Request("textDocument/prepareTypeHierarchy", positionParams(uri, position), &result)
+	return result, err
+}
+
+func (client *Client) Supertypes(item TypeHierarchyItem) ([]TypeHierarchyItem, error) {
+	var result []TypeHierarchyItem
+	err := client.Request("typeHierarchy/supertypes", TypeHierarchyParams{Item: item}, &result)
+	return result, err
+}
+
+func (client *Client) Subtypes(item TypeHierarchyItem) ([]TypeHierarchyItem, error) {
+	var result []TypeHierarchyItem
+	err := client.Request("typeHierarchy/subtypes", TypeHierarchyParams{Item: item}, &result)
+	return result, err
+}
+
+func (client *Client) InlayHints(uri string, range_ Range) ([]InlayHint, error) {
+	var result []InlayHint
+	err := client.Request("textDocument/inlayHint", InlayHintParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        range_,
+	}, &result)
+	return result, err
+}
+
+func (client *Client) CodeActions(uri string, range_ Range, only ...string) ([]CodeAction, error) {
+	var result []CodeAction
+	err := client.Request("textDocument/codeAction", CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        range_,
+		Context:      CodeActionContext{Diagnostics: []Diagnostic{}, Only: only},
+	}, &result)
+	return result, err
+}
+
+func positionParams(uri string, position Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     position,
+	}
+}
@@ -0,0 +1,36 @@
+package lsp
+
+const (
+	CodeActionKindQuickFix        = "quickfix"
+	CodeActionKindRefactorExtract = "refactor.extract"
+	CodeActionKindRefactorInline  = "refactor.inline"
+)
+
+type CodeActionRequest struct {
+	Request
+	Params CodeActionParams `json:"params"`
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only,omitempty"`
+}
+
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        string         `json:"kind,omitempty"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool           `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+type CodeActionResponse struct {
+	Response
+	Result []CodeAction `json:"result"`
+}
@@ -0,0 +1,30 @@
+package lsp
+
+const (
+	CompletionItemKindMethod   = 2
+	CompletionItemKindFunction = 3
+	CompletionItemKindField    = 5
+	CompletionItemKindVariable = 6
+	CompletionItemKindClass    = 7
+	CompletionItemKindKeyword  = 14
+)
+
+type CompletionRequest struct {
+	Request
+	Params CompletionParams `json:"params"`
+}
+
+type CompletionParams struct {
+	TextDocumentPositionParams
+}
+
+type CompletionResponse struct {
+	Response
+	Result []CompletionItem `json:"result"`
+}
+
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
@@ -0,0 +1,15 @@
+package lsp
+
+type DefinitionRequest struct {
+	Request
+	Params DefinitionParams `json:"params"`
+}
+
+type DefinitionParams struct {
+	TextDocumentPositionParams
+}
+
+type DefinitionResponse struct {
+	Response
+	Result *Location `json:"result"`
+}
@@ -8,5 +8,5 @@ type ErrorResponse struct {
 type Error struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
-	Data    any    `json:"data.omitempty"`
+	Data    any    `json:"data,omitempty"`
 }
@@ -0,0 +1,18 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestErrorOmitsEmptyData(t *testing.T) {
+	content, err := json.Marshal(Error{Code: -32601, Message: "method not found"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"code":-32601,"message":"method not found"}`
+	if string(content) != want {
+		t.Errorf("got %s, want %s", content, want)
+	}
+}
@@ -0,0 +1,19 @@
+package lsp
+
+type HoverRequest struct {
+	Request
+	Params HoverParams `json:"params"`
+}
+
+type HoverParams struct {
+	TextDocumentPositionParams
+}
+
+type HoverResponse struct {
+	Response
+	Result *HoverResult `json:"result"`
+}
+
+type HoverResult struct {
+	Contents string `json:"contents"`
+}
@@ -1,12 +1,18 @@
 package lsp
 
+import "encoding/json"
+
 type InitializeRequest struct {
 	Request
 	Params InitializeRequestParams `json:"params"`
 }
 
 type InitializeRequestParams struct {
-	ClientInfo *ClientInfo `json:"clientInfo"`
+	ClientInfo            *ClientInfo       `json:"clientInfo"`
+	Trace                 string            `json:"trace"`
+	InitializationOptions json.RawMessage   `json:"initializationOptions"`
+	RootURI               string            `json:"rootUri"`
+	WorkspaceFolders      []WorkspaceFolder `json:"workspaceFolders"`
 }
 
 type ClientInfo struct {
@@ -25,11 +31,15 @@ type InitializeResult struct {
 }
 
 type ServerCapabilities struct {
-	TextDocumentSync   int            `json:"textDocumentSync"`
-	HoverProvider      bool           `json:"hoverProvider"`
-	DefinitionProvider bool           `json:"definitionProvider"`
-	CodeActionProvider bool           `json:"codeActionProvider"`
-	CompletionProvider map[string]any `json:"completionProvider"`
+	TextDocumentSync       int            `json:"textDocumentSync"`
+	HoverProvider          bool           `json:"hoverProvider"`
+	DefinitionProvider     bool           `json:"definitionProvider"`
+	CodeActionProvider     bool           `json:"codeActionProvider"`
+	CompletionProvider     map[string]any `json:"completionProvider"`
+	DocumentSymbolProvider bool           `json:"documentSymbolProvider"`
+	CallHierarchyProvider  bool           `json:"callHierarchyProvider"`
+	TypeHierarchyProvider  bool           `json:"typeHierarchyProvider"`
+	InlayHintProvider      bool           `json:"inlayHintProvider"`
 }
 
 type ServerInfo struct {
@@ -45,11 +55,15 @@ func NewInitializeResponse(id int) InitializeResponse {
 		},
 		Result: InitializeResult{
 			ServerCapabilities: ServerCapabilities{
-				TextDocumentSync:   1,
-				HoverProvider:      true,
-				DefinitionProvider: true,
-				CodeActionProvider: true,
-				CompletionProvider: map[string]any{},
+				TextDocumentSync:       1,
+				HoverProvider:          true,
+				DefinitionProvider:     true,
+				CodeActionProvider:     true,
+				CompletionProvider:     map[string]any{"triggerCharacters": []string{"."}},
+				DocumentSymbolProvider: true,
+				CallHierarchyProvider:  true,
+				TypeHierarchyProvider:  true,
+				InlayHintProvider:      true,
 			},
 			ServerInfo: ServerInfo{
 				Name:    "lox_lsp",
@@ -74,6 +74,19 @@ type PublishDiagnosticsNotification struct {
 	Params PublishDiagnosticsParams `json:"params"`
 }
 
+func NewPublishDiagnosticsNotification(uri string, diagnostics []Diagnostic) PublishDiagnosticsNotification {
+	return PublishDiagnosticsNotification{
+		Notification: Notification{
+			RPC:    "2.0",
+			Method: "textDocument/publishDiagnostics",
+		},
+		Params: PublishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: diagnostics,
+		},
+	}
+}
+
 type PublishDiagnosticsParams struct {
 	URI         string       `json:"uri"`
 	Diagnostics []Diagnostic `json:"diagnostics"`
@@ -84,8 +97,28 @@ type Diagnostic struct {
 	Severity int    `json:"severity"`
 	Source   string `json:"source"`
 	Message  string `json:"message"`
+	Tags     []int  `json:"tags,omitempty"`
+
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
 }
 
+type DiagnosticRelatedInformation struct {
+	Location Location `json:"location"`
+	Message  string   `json:"message"`
+}
+
+const (
+	DiagnosticSeverityError       = 1
+	DiagnosticSeverityWarning     = 2
+	DiagnosticSeverityInformation = 3
+	DiagnosticSeverityHint        = 4
+)
+
+const (
+	DiagnosticTagUnnecessary = 1
+	DiagnosticTagDeprecated  = 2
+)
+
 func NewDiagnostic(range_ Range, severity int, source string, message string) Diagnostic {
 	return Diagnostic{
 		Range:    range_,
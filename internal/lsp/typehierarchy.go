@@ -0,0 +1,38 @@
+package lsp
+
+type TypeHierarchyPrepareRequest struct {
+	Request
+	Params TypeHierarchyPrepareParams `json:"params"`
+}
+
+type TypeHierarchyPrepareParams struct {
+	TextDocumentPositionParams
+}
+
+type TypeHierarchyItem struct {
+	Name           string `json:"name"`
+	Kind           int    `json:"kind"`
+	Detail         string `json:"detail,omitempty"`
+	URI            string `json:"uri"`
+	Range          Range  `json:"range"`
+	SelectionRange Range  `json:"selectionRange"`
+}
+
+type TypeHierarchySupertypesRequest struct {
+	Request
+	Params TypeHierarchyParams `json:"params"`
+}
+
+type TypeHierarchySubtypesRequest struct {
+	Request
+	Params TypeHierarchyParams `json:"params"`
+}
+
+type TypeHierarchyParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+type TypeHierarchyResponse struct {
+	Response
+	Result []TypeHierarchyItem `json:"result"`
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"runtime/debug"
 
 	"github.com/neet-007/lox_lsp_first/internal/lsp"
 	"github.com/neet-007/lox_lsp_first/pkg/analysis"
@@ -13,29 +16,118 @@ import (
 )
 
 func main() {
+	traceFile := flag.String("trace-file", "", "record every JSON-RPC message to this file as JSON Lines")
+	replayFile := flag.String("replay", "", "replay a trace file and compare the replies with the recorded ones")
+	cfgFile := flag.String("cfg", "", "print the control-flow graphs of a Lox file as Graphviz DOT")
+	flag.Parse()
+
+	if *replayFile != "" {
+		os.Exit(replay(*replayFile))
+	}
+	if *cfgFile != "" {
+		os.Exit(dumpCFG(*cfgFile))
+	}
+
 	logger := getLogger("/home/moayed/personal/lox_lsp_first/logs.txt")
 	logger.Println("Starting...")
 
-	analyser := analysis.NewAnaylser()
+	var recorder *rpc.Recorder
+	if *traceFile != "" {
+		file, err := os.OpenFile(*traceFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+		if err != nil {
+			logger.Fatalf("Error: %v", err)
+		}
+		defer file.Close()
 
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Split(rpc.Split)
+		recorder = rpc.NewRecorder(file)
+	}
 
-	writer := os.Stdout
+	serve(logger, os.Stdin, NewTracer(os.Stdout, recorder), analysis.NewAnaylser())
+}
+
+func serve(logger *log.Logger, reader io.Reader, writer *Tracer, analyser *analysis.Analyser) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), rpc.MaxMessageSize)
+	scanner.Split(rpc.LoggingSplit(logger))
 
 	for scanner.Scan() {
 		msg := scanner.Bytes()
 		method, content, err := rpc.DecodeMessage(msg)
 		if err != nil {
 			logger.Printf("Error:%v", err)
+			continue
 		}
 
-		handleMessage(logger, writer, analyser, method, content)
+		writer.Begin(method, content)
+		safeHandleMessage(logger, writer, analyser, method, content)
+		if err := writer.End(content); err != nil {
+			logger.Printf("Error writing trace: %v", err)
+		}
+	}
 
+	if err := scanner.Err(); err != nil {
+		logger.Printf("Error reading messages: %v", err)
 	}
 }
 
-func handleMessage(logger *log.Logger, writer io.Writer, analyser *analysis.Analyser, method string, content []byte) {
+// safeHandleMessage keeps the server alive when handling one message panics;
+// the panic is logged so it can be turned into a fuzz seed or a test.
+func safeHandleMessage(logger *log.Logger, writer *Tracer, analyser *analysis.Analyser, method string, content []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Printf("Panic handling %s: %v\n%s", method, r, debug.Stack())
+		}
+	}()
+
+	handleMessage(logger, writer, analyser, method, content)
+}
+
+func dumpCFG(path string) int {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	analyser := analysis.NewAnaylser()
+	analyser.Analyse(source, path, log.New(io.Discard, "", 0))
+	statements := analyser.Documents()[0].Statements
+
+	if err := analysis.WriteDOT(os.Stdout, analysis.BuildCFGs(statements)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	return 0
+}
+
+func replay(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer file.Close()
+
+	mismatches, err := ReplayFile(log.New(io.Discard, "", 0), file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	for _, mismatch := range mismatches {
+		fmt.Print(mismatch)
+	}
+
+	if len(mismatches) > 0 {
+		fmt.Printf("%d of the recorded messages replied differently\n", len(mismatches))
+		return 1
+	}
+
+	return 0
+}
+
+func handleMessage(logger *log.Logger, writer *Tracer, analyser *analysis.Analyser, method string, content []byte) {
 	logger.Printf("Message with method:%s\n", method)
 	switch method {
 	case "initialize":
@@ -47,14 +139,58 @@ func handleMessage(logger *log.Logger, writer io.Writer, analyser *analysis.Anal
 				return
 			}
 
-			logger.Printf("Connected to: %s %s",
-				request.Params.ClientInfo.Version, request.Params.ClientInfo.Name)
+			if request.Params.ClientInfo != nil {
+				logger.Printf("Connected to: %s %s",
+					request.Params.ClientInfo.Version, request.Params.ClientInfo.Name)
+			}
+
+			if err := analyser.Configure(request.Params.InitializationOptions); err != nil {
+				logger.Printf("initializationOptions: %s", err)
+			}
 
 			response := lsp.NewInitializeResponse(request.Id)
 			writeResponse(writer, response)
+			writer.SetLevel(request.Params.Trace)
+
+			roots := []string{}
+			for _, folder := range request.Params.WorkspaceFolders {
+				roots = append(roots, folder.URI)
+			}
+			if len(roots) == 0 && request.Params.RootURI != "" {
+				roots = append(roots, request.Params.RootURI)
+			}
+			logger.Printf("indexed %d files", analyser.Index(roots, logger))
 
 			logger.Println("reply sent")
 		}
+	case "$/setTrace":
+		{
+			var notification lsp.SetTraceNotification
+			if err := json.Unmarshal(content, &notification); err != nil {
+				logger.Printf("$/setTrace: %s", err)
+				return
+			}
+
+			writer.SetLevel(notification.Params.Value)
+		}
+	case "workspace/didChangeConfiguration":
+		{
+			var notification lsp.DidChangeConfigurationNotification
+			if err := json.Unmarshal(content, &notification); err != nil {
+				logger.Printf("workspace/didChangeConfiguration: %s", err)
+				return
+			}
+
+			if err := analyser.Configure(notification.Params.Settings); err != nil {
+				logger.Printf("workspace/didChangeConfiguration: %s", err)
+				return
+			}
+
+			for _, document := range analyser.Documents() {
+				diagnostics := analyser.Analyse(document.Source, document.Uri, logger)
+				writeResponse(writer, lsp.NewPublishDiagnosticsNotification(document.Uri, diagnostics))
+			}
+		}
 	case "textDocument/didOpen":
 		{
 			var didOpenTextDocumentNotification lsp.DidOpenTextDocumentNotification
@@ -63,20 +199,11 @@ func handleMessage(logger *log.Logger, writer io.Writer, analyser *analysis.Anal
 				return
 			}
 			logger.Printf("text document with uri:%s\n", didOpenTextDocumentNotification.Params.TextDocument.URI)
-			analyser.Analyse([]byte(didOpenTextDocumentNotification.Params.TextDocument.Text),
+			diagnostics := analyser.Analyse([]byte(didOpenTextDocumentNotification.Params.TextDocument.Text),
 				didOpenTextDocumentNotification.Params.TextDocument.URI,
 				logger)
-			/*
-				writeResponse(writer, lsp.PublishDiagnosticsNotification{
-					Notification: lsp.Notification{
-						RPC:    "2.0",
-						Method: "textDocument/publishDiagnostics",
-					},
-					Params: lsp.PublishDiagnosticsParams{
-						URI: didOpenTextDocumentNotification.Params.TextDocument.URI,
-					},
-				})
-			*/
+			writeResponse(writer, lsp.NewPublishDiagnosticsNotification(
+				didOpenTextDocumentNotification.Params.TextDocument.URI, diagnostics))
 		}
 	case "textDocument/didChange":
 		{
@@ -92,20 +219,137 @@ func handleMessage(logger *log.Logger, writer io.Writer, analyser *analysis.Anal
 				combinedText += change.Text
 			}
 
-			analyser.Analyse(([]byte(combinedText)),
+			diagnostics := analyser.Analyse(([]byte(combinedText)),
 				didChangeTextDocumentNotification.Params.TextDocument.URI,
 				logger)
-			/*
-				writeResponse(writer, lsp.PublishDiagnosticsNotification{
-					Notification: lsp.Notification{
-						RPC:    "2.0",
-						Method: "textDocument/publishDiagnostics",
-					},
-					Params: lsp.PublishDiagnosticsParams{
-						URI: didChangeTextDocumentNotification.Params.TextDocument.URI,
-					},
-				})
-			*/
+			writeResponse(writer, lsp.NewPublishDiagnosticsNotification(
+				didChangeTextDocumentNotification.Params.TextDocument.URI, diagnostics))
+		}
+	case "textDocument/hover":
+		{
+			var request lsp.HoverRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/hover: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.Hover(request.Id,
+				request.Params.TextDocument.URI, request.Params.Position))
+		}
+	case "textDocument/definition":
+		{
+			var request lsp.DefinitionRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/definition: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.Definition(request.Id,
+				request.Params.TextDocument.URI, request.Params.Position))
+		}
+	case "textDocument/completion":
+		{
+			var request lsp.CompletionRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/completion: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.Completion(request.Id,
+				request.Params.TextDocument.URI, request.Params.Position))
+		}
+	case "textDocument/prepareCallHierarchy":
+		{
+			var request lsp.CallHierarchyPrepareRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/prepareCallHierarchy: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.PrepareCallHierarchy(request.Id,
+				request.Params.TextDocument.URI, request.Params.Position))
+		}
+	case "callHierarchy/incomingCalls":
+		{
+			var request lsp.CallHierarchyIncomingCallsRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("callHierarchy/incomingCalls: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.IncomingCalls(request.Id, request.Params.Item))
+		}
+	case "callHierarchy/outgoingCalls":
+		{
+			var request lsp.CallHierarchyOutgoingCallsRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("callHierarchy/outgoingCalls: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.OutgoingCalls(request.Id, request.Params.Item))
+		}
+	case "textDocument/prepareTypeHierarchy":
+		{
+			var request lsp.TypeHierarchyPrepareRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/prepareTypeHierarchy: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.PrepareTypeHierarchy(request.Id,
+				request.Params.TextDocument.URI, request.Params.Position))
+		}
+	case "typeHierarchy/supertypes":
+		{
+			var request lsp.TypeHierarchySupertypesRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("typeHierarchy/supertypes: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.Supertypes(request.Id, request.Params.Item))
+		}
+	case "typeHierarchy/subtypes":
+		{
+			var request lsp.TypeHierarchySubtypesRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("typeHierarchy/subtypes: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.Subtypes(request.Id, request.Params.Item))
+		}
+	case "textDocument/documentSymbol":
+		{
+			var request lsp.DocumentSymbolRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/documentSymbol: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.DocumentSymbols(request.Id, request.Params.TextDocument.URI))
+		}
+	case "textDocument/codeAction":
+		{
+			var request lsp.CodeActionRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/codeAction: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.CodeActions(request.Id, request.Params.TextDocument.URI,
+				request.Params.Range, request.Params.Context.Only))
+		}
+	case "textDocument/inlayHint":
+		{
+			var request lsp.InlayHintRequest
+			if err := json.Unmarshal(content, &request); err != nil {
+				logger.Printf("textDocument/inlayHint: %s", err)
+				return
+			}
+
+			writeResponse(writer, analyser.InlayHints(request.Id, request.Params.TextDocument.URI, request.Params.Range))
 		}
 	}
 
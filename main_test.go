@@ -0,0 +1,1013 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+	"github.com/neet-007/lox_lsp_first/pkg/analysis"
+)
+
+func newClient(t *testing.T) *lsp.Client {
+	t.Helper()
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	go func() {
+		serve(log.New(io.Discard, "", 0), serverReader, NewTracer(serverWriter, nil), analysis.NewAnaylser())
+		serverWriter.Close()
+	}()
+	t.Cleanup(func() {
+		clientWriter.Close()
+	})
+
+	return lsp.NewClient(clientReader, clientWriter)
+}
+
+func startServer(t *testing.T) *lsp.Client {
+	t.Helper()
+
+	client := newClient(t)
+	if _, err := client.Initialize(lsp.TraceOff); err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func readFixture(t *testing.T, path string) (string, string) {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return "file:///" + filepath.ToSlash(path), string(content)
+}
+
+// positionOf finds the nth occurrence (from 0) of needle in text and returns
+// the position of its first character.
+func positionOf(t *testing.T, text string, needle string, nth int) lsp.Position {
+	t.Helper()
+
+	for line, content := range strings.Split(text, "\n") {
+		offset := 0
+		for {
+			index := strings.Index(content[offset:], needle)
+			if index < 0 {
+				break
+			}
+			if nth == 0 {
+				return lsp.Position{Line: line, Character: offset + index}
+			}
+			nth--
+			offset += index + len(needle)
+		}
+	}
+
+	t.Fatalf("%q not found", needle)
+	return lsp.Position{}
+}
+
+var markerPattern = regexp.MustCompile(`// (error|warning): `)
+
+// expectedDiagnostics reads the "// error: message" and "// warning: message"
+// markers of a fixture. A line may carry several markers, in the order they
+// are reported.
+func expectedDiagnostics(text string) []string {
+	expected := []string{}
+	for line, content := range strings.Split(text, "\n") {
+		markers := markerPattern.FindAllStringSubmatchIndex(content, -1)
+		for i, marker := range markers {
+			end := len(content)
+			if i+1 < len(markers) {
+				end = markers[i+1][0]
+			}
+			severity := content[marker[2]:marker[3]]
+			message := strings.TrimSpace(content[marker[1]:end])
+			expected = append(expected, fmt.Sprintf("%d: %s: %s", line, severity, message))
+		}
+	}
+
+	return expected
+}
+
+func formatDiagnostic(diagnostic lsp.Diagnostic) string {
+	severity := "error"
+	if diagnostic.Severity == lsp.DiagnosticSeverityWarning {
+		severity = "warning"
+	}
+
+	return fmt.Sprintf("%d: %s: %s", diagnostic.Range.Start.Line, severity, diagnostic.Message)
+}
+
+func TestInitialize(t *testing.T) {
+	client := newClient(t)
+	result, err := client.Initialize(lsp.TraceOff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capabilities := result.ServerCapabilities
+	if !capabilities.HoverProvider || !capabilities.DefinitionProvider || capabilities.CompletionProvider == nil {
+		t.Errorf("missing capabilities: %+v", capabilities)
+	}
+	if result.ServerInfo.Name != "lox_lsp" {
+		t.Errorf("server name = %q", result.ServerInfo.Name)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/diagnostics/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			client := startServer(t)
+			uri, text := readFixture(t, fixture)
+
+			if err := client.OpenDocument(uri, text); err != nil {
+				t.Fatal(err)
+			}
+			diagnostics, err := client.AwaitDiagnostics(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sort.SliceStable(diagnostics, func(i, j int) bool {
+				return diagnostics[i].Range.Start.Line < diagnostics[j].Range.Start.Line
+			})
+
+			got := []string{}
+			for _, diagnostic := range diagnostics {
+				got = append(got, formatDiagnostic(diagnostic))
+			}
+
+			want := expectedDiagnostics(text)
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDidChange(t *testing.T) {
+	client := startServer(t)
+	uri := "file:///change.lox"
+
+	if err := client.OpenDocument(uri, "print missing;\n"); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, err := client.AwaitDiagnostics(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 1 {
+		t.Fatalf("got %d diagnostics before the change, want 1", len(diagnostics))
+	}
+
+	if err := client.ChangeDocument(uri, 2, "var missing = 1;\nprint missing;\n"); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, err = client.AwaitDiagnostics(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("got %v after the change, want none", diagnostics)
+	}
+}
+
+// unnecessary returns the diagnostics that mark code as unused.
+func unnecessary(diagnostics []lsp.Diagnostic) []lsp.Diagnostic {
+	found := []lsp.Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if reflect.DeepEqual(diagnostic.Tags, []int{lsp.DiagnosticTagUnnecessary}) {
+			found = append(found, diagnostic)
+		}
+	}
+
+	return found
+}
+
+func TestUnusedParametersSetting(t *testing.T) {
+	client := startServer(t)
+	uri := "file:///unused.lox"
+
+	if err := client.OpenDocument(uri, "fun f(a) {}\nf(1);\n"); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, err := client.AwaitDiagnostics(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := unnecessary(diagnostics)
+	if len(found) != 1 || found[0].Message != "unused parameter 'a'" {
+		t.Fatalf("got %v, want the unused parameter", diagnostics)
+	}
+	if found[0].Severity != lsp.DiagnosticSeverityWarning {
+		t.Errorf("got severity %d, want a warning", found[0].Severity)
+	}
+
+	settings := map[string]any{"diagnostics": map[string]any{"unusedParameters": false}}
+	if err := client.ChangeConfiguration(settings); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, err = client.AwaitDiagnostics(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := unnecessary(diagnostics); len(found) != 0 {
+		t.Errorf("got %v with unused parameters turned off, want none", found)
+	}
+}
+
+func TestMalformedMessages(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	go serve(log.New(io.Discard, "", 0), serverReader, NewTracer(serverWriter, nil), analysis.NewAnaylser())
+	defer clientWriter.Close()
+
+	garbage := []string{
+		"Content-Length: -4\r\n\r\n",
+		"Content-Length: nope\r\n\r\n{}",
+		"Content-Type: text/plain\r\n\r\n",
+		"Content-Length: 6\r\n\r\n{\"id\":",
+		"Content-Length: 1099511627776\r\n\r\n{\"id\":",
+		strings.Repeat("x", 10000),
+	}
+	for _, message := range garbage {
+		if _, err := clientWriter.Write([]byte(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := lsp.NewClient(clientReader, clientWriter)
+	if _, err := client.Initialize(lsp.TraceOff); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHover(t *testing.T) {
+	tests := []struct {
+		fixture string
+		needle  string
+		nth     int
+		want    string
+	}{
+		{"functions.lox", "add", 1, "fun add(a, b)"},
+		{"functions.lox", "a + b", 0, "parameter a of add"},
+		{"functions.lox", "base;", 0, "var base: number"},
+		{"functions.lox", "factor +", 0, "var factor: number"},
+		{"classes.lox", "Shape {", 1, "class Shape\nfields: name"},
+		{"classes.lox", "Square", 0, "class Square < Shape\nfields: side, name"},
+		{"classes.lox", "area", 0, "method Square.area()"},
+		{"classes.lox", "Square(3)", 0, "class Square < Shape\nfields: side, name"},
+		{"classes.lox", "area();", 0, "method Square.area()"},
+		{"classes.lox", "name;", 1, "field Shape.name"},
+		{"classes.lox", "side *", 0, "field Square.side"},
+		{"partial.lox", "wave", 0, "method Greeter.wave()"},
+		{"partial.lox", "name", 0, "parameter name of greet"},
+		{"partial.lox", "greeter.", 0, "var greeter: Greeter"},
+		{"types.lox", "label;", 0, "var label: nil"},
+		{"types.lox", "label;", 1, "var label: string | nil"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%s", test.fixture, test.needle), func(t *testing.T) {
+			client := startServer(t)
+			uri, text := readFixture(t, filepath.Join("testdata", "programs", test.fixture))
+			if err := client.OpenDocument(uri, text); err != nil {
+				t.Fatal(err)
+			}
+
+			hover, err := client.Hover(uri, positionOf(t, text, test.needle, test.nth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hover == nil {
+				t.Fatalf("no hover, want %q", test.want)
+			}
+			if hover.Contents != test.want {
+				t.Errorf("hover = %q, want %q", hover.Contents, test.want)
+			}
+		})
+	}
+}
+
+func TestHoverOutsideNames(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/functions.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	hover, err := client.Hover(uri, positionOf(t, text, "print", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hover != nil {
+		t.Errorf("hover on a keyword = %q, want none", hover.Contents)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		usage       string
+		usageNth    int
+		declaration string
+		declNth     int
+	}{
+		{"functions.lox", "add(1", 0, "add", 0},
+		{"functions.lox", "add(3", 0, "add", 0},
+		{"functions.lox", "b;", 0, "b)", 0},
+		{"functions.lox", "base;", 0, "base", 0},
+		{"functions.lox", "factor +", 0, "factor", 0},
+		{"classes.lox", "Shape {", 1, "Shape", 0},
+		{"classes.lox", "square.", 0, "square", 1},
+		{"classes.lox", "side *", 0, "side = side", 0},
+		{"classes.lox", "area();", 0, "area", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%s", test.fixture, test.usage), func(t *testing.T) {
+			client := startServer(t)
+			uri, text := readFixture(t, filepath.Join("testdata", "programs", test.fixture))
+			if err := client.OpenDocument(uri, text); err != nil {
+				t.Fatal(err)
+			}
+
+			location, err := client.Definition(uri, positionOf(t, text, test.usage, test.usageNth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if location == nil {
+				t.Fatal("no definition")
+			}
+
+			want := positionOf(t, text, test.declaration, test.declNth)
+			if location.URI != uri || location.Range.Start != want {
+				t.Errorf("definition = %s %+v, want %+v", location.URI, location.Range.Start, want)
+			}
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/classes.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := client.Completion(uri, positionOf(t, text, "print", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[string]int{}
+	for _, item := range items {
+		kinds[item.Label] = item.Kind
+	}
+
+	want := map[string]int{
+		"Shape":  lsp.CompletionItemKindClass,
+		"Square": lsp.CompletionItemKindClass,
+		"square": lsp.CompletionItemKindVariable,
+		"while":  lsp.CompletionItemKindKeyword,
+		"class":  lsp.CompletionItemKindKeyword,
+	}
+	for label, kind := range want {
+		if kinds[label] != kind {
+			t.Errorf("completion %q has kind %d, want %d", label, kinds[label], kind)
+		}
+	}
+}
+
+func TestMemberCompletion(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/classes.lox")
+	text += "square.\n"
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		needle string
+		nth    int
+		offset int
+		want   []string
+	}{
+		{"square.", 1, len("square."), []string{"side", "name", "init", "area", "describe"}},
+		{"square.area", 0, len("square.ar"), []string{"side", "name", "init", "area", "describe"}},
+		{"this.name;", 0, len("this."), []string{"name", "init", "describe"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.needle, func(t *testing.T) {
+			position := positionOf(t, text, test.needle, test.nth)
+			position.Character += test.offset
+			items, err := client.Completion(uri, position)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			labels := []string{}
+			for _, item := range items {
+				labels = append(labels, item.Label)
+			}
+			if !reflect.DeepEqual(labels, test.want) {
+				t.Errorf("completion = %v, want %v", labels, test.want)
+			}
+		})
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/classes.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	symbols, err := client.DocumentSymbols(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var format func(symbols []lsp.DocumentSymbol, indent string) string
+	format = func(symbols []lsp.DocumentSymbol, indent string) string {
+		var builder strings.Builder
+		for _, symbol := range symbols {
+			fmt.Fprintf(&builder, "%s%d %s\n", indent, symbol.Kind, symbol.Detail)
+			builder.WriteString(format(symbol.Children, indent+"  "))
+		}
+		return builder.String()
+	}
+
+	want := `5 class Shape
+  8 field Shape.name
+  6 method Shape.init(name)
+  6 method Shape.describe()
+5 class Square < Shape
+  8 field Square.side
+  6 method Square.init(side)
+  6 method Square.area()
+13 var square
+`
+	if got := format(symbols, ""); got != want {
+		t.Errorf("symbols:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCallHierarchy(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/calls.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	prepare := func(needle string, nth int) lsp.CallHierarchyItem {
+		t.Helper()
+		items, err := client.PrepareCallHierarchy(uri, positionOf(t, text, needle, nth))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("prepare %q = %v, want one item", needle, items)
+		}
+		return items[0]
+	}
+	format := func(item lsp.CallHierarchyItem, ranges []lsp.Range) string {
+		lines := []string{}
+		for _, r := range ranges {
+			lines = append(lines, fmt.Sprintf("%d:%d", r.Start.Line, r.Start.Character))
+		}
+		return fmt.Sprintf("%s (%s) at %s", item.Name, item.Detail, strings.Join(lines, " "))
+	}
+
+	incoming := []struct {
+		needle string
+		nth    int
+		want   []string
+	}{
+		{"square", 0, []string{
+			"sumOfSquares (fun sumOfSquares(a, b)) at 5:9 5:21",
+			"reset (method Counter.reset()) at 15:17",
+			"<top level> () at 28:6",
+		}},
+		{"reset", 1, []string{
+			"init (method Counter.init()) at 11:9",
+			"reset (method Loud.reset()) at 21:10",
+		}},
+		{"reset()", 2, []string{
+			"<top level> () at 27:8",
+		}},
+		{"Loud", 0, []string{
+			"<top level> () at 26:14",
+		}},
+	}
+	for _, test := range incoming {
+		t.Run("incoming "+test.needle, func(t *testing.T) {
+			calls, err := client.IncomingCalls(prepare(test.needle, test.nth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, call := range calls {
+				got = append(got, format(call.From, call.FromRanges))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("incoming calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+
+	outgoing := []struct {
+		needle string
+		nth    int
+		want   []string
+	}{
+		{"sumOfSquares", 0, []string{
+			"square (fun square(n)) at 5:9 5:21",
+		}},
+		{"reset()", 2, []string{
+			"reset (method Counter.reset()) at 21:10",
+			"sumOfSquares (fun sumOfSquares(a, b)) at 22:10",
+		}},
+		{"Counter", 0, []string{
+			"reset (method Counter.reset()) at 11:9",
+		}},
+	}
+	for _, test := range outgoing {
+		t.Run("outgoing "+test.needle, func(t *testing.T) {
+			calls, err := client.OutgoingCalls(prepare(test.needle, test.nth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, call := range calls {
+				got = append(got, format(call.To, call.FromRanges))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("outgoing calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestTypeHierarchy(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/classes.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	prepare := func(needle string) lsp.TypeHierarchyItem {
+		t.Helper()
+		items, err := client.PrepareTypeHierarchy(uri, positionOf(t, text, needle, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("prepare %q = %v, want one item", needle, items)
+		}
+		return items[0]
+	}
+	format := func(items []lsp.TypeHierarchyItem) []string {
+		got := []string{}
+		for _, item := range items {
+			got = append(got, fmt.Sprintf("%s (%s)", item.Name, item.Detail))
+		}
+		return got
+	}
+
+	shape, square := prepare("Shape"), prepare("Square")
+	tests := []struct {
+		name  string
+		items func() ([]lsp.TypeHierarchyItem, error)
+		want  []string
+	}{
+		{"supertypes of Square", func() ([]lsp.TypeHierarchyItem, error) { return client.Supertypes(square) }, []string{
+			"Shape (class Shape; methods init(name), describe())",
+		}},
+		{"supertypes of Shape", func() ([]lsp.TypeHierarchyItem, error) { return client.Supertypes(shape) }, []string{}},
+		{"subtypes of Shape", func() ([]lsp.TypeHierarchyItem, error) { return client.Subtypes(shape) }, []string{
+			"Square (class Square < Shape; methods init(side), area())",
+		}},
+		{"subtypes of Square", func() ([]lsp.TypeHierarchyItem, error) { return client.Subtypes(square) }, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items, err := test.items()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := format(items); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("items:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+
+	// Variables are not types.
+	items, err := client.PrepareTypeHierarchy(uri, positionOf(t, text, "square =", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("prepare square = %v, want none", items)
+	}
+}
+
+func TestTypeHierarchyAcrossFiles(t *testing.T) {
+	client := startServer(t)
+	library, libraryText := readFixture(t, "testdata/workspace/library.lox")
+	main, mainText := readFixture(t, "testdata/workspace/main.lox")
+	for uri, text := range map[string]string{library: libraryText, main: mainText} {
+		if err := client.OpenDocument(uri, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prepare := func(uri string, text string, needle string) lsp.TypeHierarchyItem {
+		t.Helper()
+		items, err := client.PrepareTypeHierarchy(uri, positionOf(t, text, needle, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("prepare %q = %v, want one item", needle, items)
+		}
+		return items[0]
+	}
+
+	subtypes, err := client.Subtypes(prepare(library, libraryText, "Greeter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subtypes) != 1 || subtypes[0].Name != "Host" || subtypes[0].URI != main {
+		t.Errorf("subtypes of Greeter = %+v, want Host in %s", subtypes, main)
+	}
+
+	supertypes, err := client.Supertypes(prepare(main, mainText, "Host"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(supertypes) != 1 || supertypes[0].Name != "Greeter" || supertypes[0].URI != library {
+		t.Errorf("supertypes of Host = %+v, want Greeter in %s", supertypes, library)
+	}
+}
+
+func TestInlayHints(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/calls.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+	everything := lsp.Range{End: lsp.Position{Line: 100}}
+
+	hints := func() []string {
+		t.Helper()
+		hints, err := client.InlayHints(uri, everything)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, hint := range hints {
+			got = append(got, fmt.Sprintf("%d:%d %s", hint.Position.Line, hint.Position.Character, hint.Label))
+		}
+		return got
+	}
+
+	want := []string{
+		"5:16 n:",
+		"5:28 n:",
+		"15:24 n:",
+		"22:23 a:",
+		"22:26 b:",
+		"28:13 n:",
+	}
+	if got := hints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hints:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	settings := map[string]any{"inlayHints": map[string]any{
+		"parameterNames":  false,
+		"variableTypes":   true,
+		"implicitReturns": true,
+	}}
+	if err := client.ChangeConfiguration(settings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.AwaitDiagnostics(uri); err != nil {
+		t.Fatal(err)
+	}
+
+	want = []string{
+		"16:2 return nil",
+		"23:2 return nil",
+		"26:11 : Loud",
+	}
+	if got := hints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hints:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCodeActions(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/partial.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	position := positionOf(t, text, "print \"bye\"", 0)
+	line := lsp.Range{Start: position, End: lsp.Position{Line: position.Line, Character: 100}}
+	actions, err := client.CodeActions(uri, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0].Title != "Insert ';'" || actions[0].Kind != lsp.CodeActionKindQuickFix {
+		t.Fatalf("actions = %+v, want one to insert ';'", actions)
+	}
+
+	want := []lsp.TextEdit{{
+		Range:   lsp.Range{Start: lsp.Position{Line: 6, Character: 15}, End: lsp.Position{Line: 6, Character: 15}},
+		NewText: ";",
+	}}
+	if got := actions[0].Edit.Changes[uri]; !reflect.DeepEqual(got, want) {
+		t.Errorf("edits = %+v, want %+v", got, want)
+	}
+	if len(actions[0].Diagnostics) != 1 {
+		t.Errorf("got %d diagnostics, want the one fixed", len(actions[0].Diagnostics))
+	}
+
+	actions, err = client.CodeActions(uri, line, "refactor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("refactors = %+v, want none", actions)
+	}
+}
+
+func TestWorkspaceIndex(t *testing.T) {
+	root, err := filepath.Abs("testdata/programs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClient(t)
+	if _, err := client.InitializeWorkspace(lsp.TraceOff, "file://"+filepath.ToSlash(root)); err != nil {
+		t.Fatal(err)
+	}
+
+	// calls.lox is never opened; the server read it from disk.
+	uri := "file://" + filepath.ToSlash(filepath.Join(root, "calls.lox"))
+	_, text := readFixture(t, "testdata/programs/calls.lox")
+	items, err := client.PrepareCallHierarchy(uri, positionOf(t, text, "square", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Name != "square" || items[0].URI != uri {
+		t.Fatalf("prepare = %+v, want square in %s", items, uri)
+	}
+
+	calls, err := client.IncomingCalls(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 3 {
+		t.Errorf("got %d callers of square, want 3", len(calls))
+	}
+}
+
+func TestCallHierarchyAcrossFiles(t *testing.T) {
+	root, err := filepath.Abs("testdata/workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClient(t)
+	if _, err := client.InitializeWorkspace(lsp.TraceOff, "file://"+filepath.ToSlash(root)); err != nil {
+		t.Fatal(err)
+	}
+
+	// main.lox is open and calls what library.lox, which is only indexed,
+	// declares.
+	library := "file://" + filepath.ToSlash(filepath.Join(root, "library.lox"))
+	main := "file://" + filepath.ToSlash(filepath.Join(root, "main.lox"))
+	_, libraryText := readFixture(t, "testdata/workspace/library.lox")
+	_, mainText := readFixture(t, "testdata/workspace/main.lox")
+	if err := client.OpenDocument(main, mainText); err != nil {
+		t.Fatal(err)
+	}
+
+	prepare := func(uri string, text string, needle string) lsp.CallHierarchyItem {
+		t.Helper()
+		items, err := client.PrepareCallHierarchy(uri, positionOf(t, text, needle, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("prepare %q = %v, want one item", needle, items)
+		}
+		return items[0]
+	}
+	format := func(item lsp.CallHierarchyItem, ranges []lsp.Range) string {
+		lines := []string{}
+		for _, r := range ranges {
+			lines = append(lines, fmt.Sprintf("%d:%d", r.Start.Line, r.Start.Character))
+		}
+		return fmt.Sprintf("%s in %s at %s", item.Name, filepath.Base(item.URI), strings.Join(lines, " "))
+	}
+
+	incoming, err := client.IncomingCalls(prepare(library, libraryText, "greet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, call := range incoming {
+		got = append(got, format(call.From, call.FromRanges))
+	}
+	want := []string{
+		"shout in library.lox at 5:8",
+		"welcome in main.lox at 1:8",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incoming calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	outgoing, err := client.OutgoingCalls(prepare(main, mainText, "welcome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = []string{}
+	for _, call := range outgoing {
+		got = append(got, format(call.To, call.FromRanges))
+	}
+	want = []string{
+		"greet in library.lox at 1:8",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outgoing calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCompletionScopes(t *testing.T) {
+	client := startServer(t)
+	uri, text := readFixture(t, "testdata/programs/functions.lox")
+	if err := client.OpenDocument(uri, text); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		needle  string
+		visible []string
+		hidden  []string
+	}{
+		{"a + b", []string{"a", "b", "add", "base", "scale"}, []string{"value", "factor"}},
+		{"var factor", []string{"value", "base"}, []string{"a", "factor"}},
+		{"value * factor", []string{"value", "factor"}, []string{"b"}},
+		{"print add", []string{"add", "scale", "base"}, []string{"a", "value", "factor"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.needle, func(t *testing.T) {
+			items, err := client.Completion(uri, positionOf(t, text, test.needle, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			labels := map[string]bool{}
+			for _, item := range items {
+				labels[item.Label] = true
+			}
+			for _, label := range test.visible {
+				if !labels[label] {
+					t.Errorf("%q is not offered", label)
+				}
+			}
+			for _, label := range test.hidden {
+				if labels[label] {
+					t.Errorf("%q is offered outside its scope", label)
+				}
+			}
+		})
+	}
+}
+
+func TestSetTrace(t *testing.T) {
+	client := startServer(t)
+	if err := client.SetTrace(lsp.TraceVerbose); err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "file:///trace.lox"
+	if err := client.OpenDocument(uri, "print 1;\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	var trace lsp.LogTraceParams
+	if err := client.AwaitNotification("$/logTrace", &trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.Message != "Received notification 'textDocument/didOpen'." {
+		t.Errorf("trace message = %q", trace.Message)
+	}
+	if !strings.HasPrefix(trace.Verbose, "Params: ") {
+		t.Errorf("verbose trace = %q", trace.Verbose)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	transcripts, err := filepath.Glob("testdata/transcripts/*.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, transcript := range transcripts {
+		t.Run(filepath.Base(transcript), func(t *testing.T) {
+			file, err := os.Open(transcript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			mismatches, err := ReplayFile(log.New(io.Discard, "", 0), file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, mismatch := range mismatches {
+				t.Error(mismatch)
+			}
+		})
+	}
+}
+
+func TestReplayMismatchLine(t *testing.T) {
+	transcript := `
+
+{"direction":"recv","method":"shutdown","id":1,"message":{"jsonrpc":"2.0","id":1,"method":"shutdown"}}
+{"direction":"send","id":1,"message":{"jsonrpc":"2.0","id":1,"result":"wrong"}}
+`
+
+	mismatches, err := ReplayFile(log.New(io.Discard, "", 0), strings.NewReader(transcript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Line != 3 {
+		t.Errorf("mismatches = %v, want one at line 3", mismatches)
+	}
+}
+
+func TestReplaySkipsIndex(t *testing.T) {
+	root, err := filepath.Abs("testdata/workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := "file://" + filepath.ToSlash(filepath.Join(root, "library.lox"))
+
+	// No replies are recorded, so both messages mismatch and the reply the
+	// server made shows in Actual: greet is unknown when nothing was read.
+	transcript := fmt.Sprintf(`{"direction":"recv","method":"initialize","id":1,"message":{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":%[1]q}}}
+{"direction":"recv","method":"textDocument/prepareCallHierarchy","id":2,"message":{"jsonrpc":"2.0","id":2,"method":"textDocument/prepareCallHierarchy","params":{"textDocument":{"uri":%[2]q},"position":{"line":0,"character":4}}}}
+`, "file://"+filepath.ToSlash(root), uri)
+
+	mismatches, err := ReplayFile(log.New(io.Discard, "", 0), strings.NewReader(transcript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 {
+		t.Fatalf("mismatches = %v, want one per message", mismatches)
+	}
+	if got, want := mismatches[1].Actual, []string{`{"id":2,"jsonrpc":"2.0","result":[]}`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replies = %v, want %v", got, want)
+	}
+}
+
+func TestIdNormalizer(t *testing.T) {
+	normalizer := newIdNormalizer()
+
+	got := []int{
+		normalizer.normalize(float64(1)),
+		normalizer.normalize("1"),
+		normalizer.normalize(float64(1)),
+		normalizer.normalize("a"),
+	}
+	if want := []int{1, 2, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ids = %v, want %v", got, want)
+	}
+}
@@ -15,7 +15,7 @@ func NewLoxClass(name string, superClass *LoxClass, methods map[string]*LoxFunct
 }
 
 func (class *LoxClass) findMethod(name string) *LoxFunction {
-	if val, ok := class.superClass.methods[name]; ok {
+	if val, ok := class.methods[name]; ok {
 		return val
 	}
 
@@ -0,0 +1,252 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// checkArity reports calls with the wrong number of arguments when the
+// callee is known: a function or class named directly, or a method reached
+// through this or super.
+func (analyser *Analyser) checkArity(statements []Stmt, resolver *Resolver) {
+	var class *Class
+	var visit func(node any) bool
+	visit = func(node any) bool {
+		switch node := node.(type) {
+		case *Class:
+			enclosing := class
+			class = node
+			for _, method := range node.Methods {
+				Walk(method, visit)
+			}
+			class = enclosing
+			return false
+		case *Call:
+			analyser.checkCall(node, class, resolver)
+		}
+		return true
+	}
+
+	WalkStatements(statements, visit)
+}
+
+func (analyser *Analyser) checkCall(call *Call, class *Class, resolver *Resolver) {
+	var name Token
+	var owner *Class
+	var function *Function
+
+	switch callee := call.Callee.(type) {
+	case *Variable:
+		symbol, ok := resolver.SymbolOf(callee)
+		if !ok || !constant(symbol) {
+			return
+		}
+		name = callee.Name
+
+		switch node := symbol.Node.(type) {
+		case *Function:
+			if symbol.Kind != FUNCTION_DECLARATION {
+				return
+			}
+			function = node
+		case *Class:
+			if symbol.Kind != CLASS_DECLARATION {
+				return
+			}
+			// A class takes the arguments of its initializer, or none.
+			_, initializer := findMethod(node, "init", resolver)
+			declared, arity := node.Name, 0
+			if initializer != nil {
+				declared, arity = initializer.Name, len(initializer.Params)
+			}
+			diagnostic := analyser.checkArguments(call, name, declared, functionSignature(node.Name.Lexeme, initializer), arity)
+			if diagnostic != nil && initializer != nil && len(call.Arguments) > arity {
+				analyser.addParametersFix(diagnostic, node, initializer, call.Arguments[arity:])
+			}
+			return
+		default:
+			return
+		}
+	case *Get:
+		if _, ok := callee.Object.(*This); !ok || class == nil {
+			return
+		}
+		name = callee.Name
+		owner, function = findMethod(class, callee.Name.Lexeme, resolver)
+	case *Super:
+		if class == nil {
+			return
+		}
+		name = callee.Method
+		owner, function = findMethod(superclassOf(class, resolver), callee.Method.Lexeme, resolver)
+	}
+
+	if function == nil {
+		return
+	}
+
+	signature := functionSignature(function.Name.Lexeme, function)
+	if owner != nil {
+		signature = functionSignature(owner.Name.Lexeme+"."+function.Name.Lexeme, function)
+	}
+	analyser.checkArguments(call, name, function.Name, signature, len(function.Params))
+}
+
+// constant reports whether symbol holds what it was declared as wherever it
+// is used: it is neither assigned nor declared again, as globals can be.
+func constant(symbol *Symbol) bool {
+	if isAssigned(symbol) {
+		return false
+	}
+	for _, other := range symbol.Scope.Symbols {
+		if other != symbol && other.Name == symbol.Name && other.Kind != METHOD_DECLARATION {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkArguments reports call if it does not pass arity arguments, pointing
+// at the declaration named declared. The diagnostic is nil when it does, or
+// when an argument is missing: the syntax error says enough, and what was
+// meant to be passed is not known.
+func (analyser *Analyser) checkArguments(call *Call, name Token, declared Token, signature string, arity int) *lsp.Diagnostic {
+	if len(call.Arguments) == arity {
+		return nil
+	}
+	for _, argument := range call.Arguments {
+		if _, ok := argument.(*Missing); ok {
+			return nil
+		}
+	}
+
+	noun := "arguments"
+	if arity == 1 {
+		noun = "argument"
+	}
+	diagnostic := analyser.Error(name, fmt.Sprintf("%s expects %d %s but got %d",
+		signature, arity, noun, len(call.Arguments)))
+	analyser.related(diagnostic, declared)
+	return diagnostic
+}
+
+// addParametersFix offers to add a parameter to the initializer of class
+// for each of the extra arguments, named after the argument when it is a
+// variable.
+func (analyser *Analyser) addParametersFix(diagnostic *lsp.Diagnostic, class *Class, initializer *Function, extra []Expr) {
+	last := initializer.Name
+	if len(initializer.Params) > 0 {
+		last = initializer.Params[len(initializer.Params)-1]
+	}
+	paren, ok := analyser.tokenAfter(last, RIGHT_PAREN)
+	if !ok {
+		return
+	}
+
+	taken := map[string]bool{}
+	for _, param := range initializer.Params {
+		taken[param.Lexeme] = true
+	}
+	names := []string{}
+	for i, argument := range extra {
+		name := ""
+		if variable, ok := argument.(*Variable); ok {
+			name = variable.Name.Lexeme
+		}
+		for n := len(initializer.Params) + i + 1; name == "" || taken[name]; n++ {
+			name = fmt.Sprintf("arg%d", n)
+		}
+		taken[name] = true
+		names = append(names, name)
+	}
+
+	text := strings.Join(names, ", ")
+	if len(initializer.Params) > 0 {
+		text = ", " + text
+	}
+	noun := "parameter"
+	if len(names) > 1 {
+		noun = "parameters"
+	}
+	analyser.fix(diagnostic, fmt.Sprintf("Add %s '%s' to %s.init", noun, strings.Join(names, "', '"), class.Name.Lexeme),
+		insertText(tokenRange(paren).Start, text))
+}
+
+// tokenAfter finds the first token of type tokenType after token.
+func (analyser *Analyser) tokenAfter(token Token, tokenType TokenType) (Token, bool) {
+	found := false
+	for _, next := range analyser.tokens {
+		if found && next.Type == tokenType {
+			return next, true
+		}
+		found = found || next == token
+	}
+
+	return Token{}, false
+}
+
+// functionSignature writes name with the parameters of function, which
+// may be nil for a class without an initializer.
+func functionSignature(name string, function *Function) string {
+	params := []string{}
+	if function != nil {
+		for _, param := range function.Params {
+			params = append(params, param.Lexeme)
+		}
+	}
+
+	return fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+}
+
+// findMethod looks for a method of class or of its superclasses, returning
+// the class that declares it. Like LoxClass.findMethod, it finds the last
+// of the methods a class declares with the same name.
+func findMethod(class *Class, name string, resolver *Resolver) (*Class, *Function) {
+	seen := map[*Class]bool{}
+	for class != nil && !seen[class] {
+		seen[class] = true
+		for _, method := range slices.Backward(class.Methods) {
+			if method.Name.Lexeme == name {
+				return class, method
+			}
+		}
+		class = superclassOf(class, resolver)
+	}
+
+	return nil, nil
+}
+
+// superclassOf returns the class that class inherits from, if its name
+// refers to a class declaration.
+func superclassOf(class *Class, resolver *Resolver) *Class {
+	if class.Superclass == nil {
+		return nil
+	}
+
+	symbol, ok := resolver.SymbolOf(class.Superclass)
+	if !ok || symbol.Kind != CLASS_DECLARATION {
+		return nil
+	}
+
+	superclass, _ := symbol.Node.(*Class)
+	return superclass
+}
+
+// knownChain reports whether every superclass of class is a class
+// declaration, so that all of its methods and fields can be known.
+func knownChain(class *Class, resolver *Resolver) bool {
+	seen := map[*Class]bool{}
+	for class != nil && !seen[class] {
+		seen[class] = true
+		if class.Superclass == nil {
+			return true
+		}
+		class = superclassOf(class, resolver)
+	}
+
+	return false
+}
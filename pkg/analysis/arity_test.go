@@ -0,0 +1,104 @@
+package analysis
+
+import "testing"
+
+func TestArity(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "functions",
+			source: `fun none() {}
+fun one(a) { return a; }
+none(1);
+one();
+one(1);
+`,
+			want: `2:0-4: none() expects 0 arguments but got 1
+3:0-3: one(a) expects 1 argument but got 0
+`,
+		},
+		{
+			name: "classes",
+			source: `class Empty {}
+class Base {
+  init(a, b) {}
+}
+class Derived < Base {}
+Empty(1);
+Base(1, 2);
+Derived(1);
+`,
+			want: `5:0-5: Empty() expects 0 arguments but got 1
+7:0-7: Derived(a, b) expects 2 arguments but got 1
+`,
+		},
+		{
+			name: "this and super",
+			source: `class Base {
+  greet(name) {}
+}
+class Derived < Base {
+  run() {
+    this.greet();
+    super.greet(1, 2);
+    this.run(1);
+    this.unknown(1);
+  }
+}
+`,
+			want: `5:9-14: Base.greet(name) expects 1 argument but got 0
+6:10-15: Base.greet(name) expects 1 argument but got 2
+7:9-12: Derived.run() expects 0 arguments but got 1
+`,
+		},
+		{
+			name: "values are not checked",
+			source: `fun f(a) {}
+var g = f;
+g();
+fun h(callback) {
+  callback(1, 2);
+}
+`,
+		},
+		{
+			name: "redeclared and reassigned globals are not checked",
+			source: `fun f(a) {}
+fun f() {}
+f();
+fun g() {}
+fun one(a) {}
+g = one;
+g(1);
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			analyser.checkArity(statements, resolver)
+
+			if got := formatDiagnostics(analyser); got != test.want {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestArityPointsAtDeclaration(t *testing.T) {
+	statements, resolver, analyser := resolve(t, "fun add(a, b) {}\nadd(1);\n")
+	analyser.checkArity(statements, resolver)
+
+	if len(analyser.diagnostics) != 1 {
+		t.Fatalf("got %d diagnostics, want 1", len(analyser.diagnostics))
+	}
+	related := analyser.diagnostics[0].RelatedInformation
+	if len(related) != 1 || related[0].Message != "'add' is declared here" ||
+		related[0].Location.Range != tokenRange(Token{Lexeme: "add", StartLine: 0, StartChar: 4, EndChar: 7}) {
+		t.Errorf("got related information %+v, want the declaration of add", related)
+	}
+}
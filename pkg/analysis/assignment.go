@@ -0,0 +1,273 @@
+package analysis
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+type AccessKind int
+
+const (
+	READ_ACCESS AccessKind = iota
+	WRITE_ACCESS
+	// DECLARE_ACCESS is a var without an initializer, which starts the
+	// variable over as unassigned.
+	DECLARE_ACCESS
+)
+
+// access is a read or a write of a variable, in the order a graph node
+// evaluates them.
+type access struct {
+	kind   AccessKind
+	symbol *Symbol
+	name   Token
+	// nilStore is a var initialized to a literal nil, which says nothing
+	// more than leaving the initializer out and is not a dead store.
+	nilStore bool
+}
+
+// checkAssignments warns about variables that may be read before anything
+// is assigned to them, and about values that are overwritten or dropped
+// without being read. Only the variables of the function the graph is for
+// are followed, and not those that a nested function refers to, since it
+// may read or assign them whenever it is called.
+func (analyser *Analyser) checkAssignments(cfg *CFG, resolver *Resolver) {
+	global := resolver.Scopes()
+	scope := global
+	if cfg.Function != nil {
+		name := cfg.Function.Name
+		scope = enclosingFunction(global.ScopeAt(lsp.Position{Line: name.StartLine, Character: name.StartChar}))
+	}
+
+	tracked := map[*Symbol]bool{}
+	isTracked := func(symbol *Symbol) bool {
+		if result, ok := tracked[symbol]; ok {
+			return result
+		}
+
+		result := (symbol.Kind == VARIABLE_DECLARATION || symbol.Kind == PARAMETER_DECLARATION) &&
+			!strings.HasPrefix(symbol.Name, "_") && enclosingFunction(symbol.Scope) == scope
+		for _, reference := range symbol.References {
+			name, _ := referenceName(reference)
+			position := lsp.Position{Line: name.StartLine, Character: name.StartChar}
+			if enclosingFunction(global.ScopeAt(position)) != scope {
+				result = false
+			}
+		}
+		tracked[symbol] = result
+		return result
+	}
+
+	reachable := cfg.Reachable()
+	accesses := map[*BasicBlock][]access{}
+	for _, block := range cfg.Blocks {
+		if !reachable[block] {
+			continue
+		}
+		for _, node := range block.Nodes {
+			for _, access := range nodeAccesses(node, resolver) {
+				if isTracked(access.symbol) {
+					accesses[block] = append(accesses[block], access)
+				}
+			}
+		}
+	}
+
+	analyser.checkUnassignedReads(cfg, reachable, accesses)
+	analyser.checkDeadStores(cfg, reachable, accesses)
+}
+
+// checkUnassignedReads follows the variables that may be unassigned
+// forward through the graph, until what enters each block stops changing.
+func (analyser *Analyser) checkUnassignedReads(cfg *CFG, reachable map[*BasicBlock]bool, accesses map[*BasicBlock][]access) {
+	in := func(block *BasicBlock, out map[*BasicBlock]map[*Symbol]bool) map[*Symbol]bool {
+		unassigned := map[*Symbol]bool{}
+		for _, edge := range block.Predecessors {
+			if reachable[edge.From] {
+				maps.Copy(unassigned, out[edge.From])
+			}
+		}
+		return unassigned
+	}
+	transfer := func(unassigned map[*Symbol]bool, access access) {
+		switch access.kind {
+		case DECLARE_ACCESS:
+			unassigned[access.symbol] = true
+		case WRITE_ACCESS:
+			delete(unassigned, access.symbol)
+		}
+	}
+
+	out := map[*BasicBlock]map[*Symbol]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, block := range cfg.Blocks {
+			if !reachable[block] {
+				continue
+			}
+
+			unassigned := in(block, out)
+			for _, access := range accesses[block] {
+				transfer(unassigned, access)
+			}
+			if previous, ok := out[block]; !ok || !maps.Equal(previous, unassigned) {
+				out[block] = unassigned
+				changed = true
+			}
+		}
+	}
+
+	reads := []Token{}
+	for _, block := range cfg.Blocks {
+		if !reachable[block] {
+			continue
+		}
+
+		unassigned := in(block, out)
+		for _, access := range accesses[block] {
+			if access.kind == READ_ACCESS && unassigned[access.symbol] {
+				reads = append(reads, access.name)
+			}
+			transfer(unassigned, access)
+		}
+	}
+
+	sort.Slice(reads, func(i, j int) bool {
+		return positionBefore(tokenRange(reads[i]).Start, tokenRange(reads[j]).Start)
+	})
+	for _, name := range reads {
+		analyser.Warning(name, fmt.Sprintf("'%s' may be read before it is assigned", name.Lexeme))
+	}
+}
+
+// checkDeadStores follows the variables whose value may still be read
+// backward through the graph, and reports the stores made while a variable
+// is not one of them. Variables that are never read at all are reported as
+// unused instead.
+func (analyser *Analyser) checkDeadStores(cfg *CFG, reachable map[*BasicBlock]bool, accesses map[*BasicBlock][]access) {
+	out := func(block *BasicBlock, in map[*BasicBlock]map[*Symbol]bool) map[*Symbol]bool {
+		live := map[*Symbol]bool{}
+		for _, edge := range block.Successors {
+			maps.Copy(live, in[edge.To])
+		}
+		return live
+	}
+
+	in := map[*BasicBlock]map[*Symbol]bool{}
+	for changed := true; changed; {
+		changed = false
+		for i := len(cfg.Blocks) - 1; i >= 0; i-- {
+			block := cfg.Blocks[i]
+			if !reachable[block] {
+				continue
+			}
+
+			live := out(block, in)
+			for _, access := range slices.Backward(accesses[block]) {
+				if access.kind == READ_ACCESS {
+					live[access.symbol] = true
+				} else {
+					delete(live, access.symbol)
+				}
+			}
+			if previous, ok := in[block]; !ok || !maps.Equal(previous, live) {
+				in[block] = live
+				changed = true
+			}
+		}
+	}
+
+	stores := []Token{}
+	for _, block := range cfg.Blocks {
+		if !reachable[block] {
+			continue
+		}
+
+		live := out(block, in)
+		for _, access := range slices.Backward(accesses[block]) {
+			switch access.kind {
+			case READ_ACCESS:
+				live[access.symbol] = true
+			case WRITE_ACCESS:
+				if !live[access.symbol] && !access.nilStore && isRead(access.symbol) {
+					stores = append(stores, access.name)
+				}
+				delete(live, access.symbol)
+			case DECLARE_ACCESS:
+				delete(live, access.symbol)
+			}
+		}
+	}
+
+	sort.Slice(stores, func(i, j int) bool {
+		return positionBefore(tokenRange(stores[i]).Start, tokenRange(stores[j]).Start)
+	})
+	for _, name := range stores {
+		analyser.Unnecessary(name, fmt.Sprintf("value assigned to '%s' is never read", name.Lexeme))
+	}
+}
+
+// nodeAccesses returns the variable accesses of a graph node. The operands
+// of and/or are nodes of their own and are left out.
+func nodeAccesses(node any, resolver *Resolver) []access {
+	accesses := []access{}
+
+	var visit func(expr Expr)
+	visit = func(expr Expr) {
+		switch expr := expr.(type) {
+		case nil, *Logical:
+			return
+		case *Variable:
+			if symbol, ok := resolver.SymbolOf(expr); ok {
+				accesses = append(accesses, access{kind: READ_ACCESS, symbol: symbol, name: expr.Name})
+			}
+			return
+		case *Assign:
+			visit(expr.Value)
+			if symbol, ok := resolver.SymbolOf(expr); ok {
+				accesses = append(accesses, access{kind: WRITE_ACCESS, symbol: symbol, name: expr.Name})
+			}
+			return
+		}
+
+		for _, child := range children(expr) {
+			visit(child)
+		}
+	}
+
+	switch node := node.(type) {
+	case *Var:
+		visit(node.Initializer)
+		// A second declaration of a name is an error, and is left out.
+		symbol, ok := resolver.DeclaredSymbol(node.Name)
+		if !ok || symbol.Token != node.Name {
+			break
+		}
+		if node.Initializer == nil {
+			accesses = append(accesses, access{kind: DECLARE_ACCESS, symbol: symbol, name: node.Name})
+		} else {
+			literal, isLiteral := node.Initializer.(*Literal)
+			accesses = append(accesses, access{kind: WRITE_ACCESS, symbol: symbol, name: node.Name,
+				nilStore: isLiteral && literal.Value == nil})
+		}
+	case *Expression:
+		visit(node.Expression)
+	case *Print:
+		visit(node.Expression)
+	case *Return:
+		visit(node.Value)
+	case *Class:
+		if node.Superclass != nil {
+			visit(node.Superclass)
+		}
+	case Expr:
+		visit(node)
+	}
+
+	return accesses
+}
@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "read before assignment",
+			source: `fun f(a) {
+  var x;
+  if (a) x = 1;
+  print x;
+  var y;
+  if (a) y = 1; else y = 2;
+  print y;
+}
+`,
+			want: `3:8-3:9: 'x' may be read before it is assigned
+`,
+		},
+		{
+			name: "assigned in one operand of or",
+			source: `fun f(a) {
+  var x;
+  if (a or (x = 1)) print x;
+  var y;
+  if ((y = 1) or a) print y;
+}
+`,
+			want: `2:26-2:27: 'x' may be read before it is assigned
+`,
+		},
+		{
+			name: "loops",
+			source: `fun f(a) {
+  var x;
+  while (a) {
+    print x;
+    x = 1;
+  }
+  var i = 0;
+  while (i < 10) i = i + 1;
+}
+`,
+			want: `3:10-3:11: 'x' may be read before it is assigned
+`,
+		},
+		{
+			name: "dead stores",
+			source: `fun f(a) {
+  var x = 1;
+  x = 2;
+  print x;
+  x = 3;
+  var y = nil;
+  if (a) y = 1; else y = 2;
+  print y;
+}
+`,
+			want: `1:6-1:7: value assigned to 'x' is never read
+4:2-4:3: value assigned to 'x' is never read
+`,
+		},
+		{
+			name: "captured variables are left alone",
+			source: `fun f() {
+  var x;
+  fun g() {
+    x = 1;
+  }
+  g();
+  print x;
+  var y = 1;
+  fun h() {
+    print y;
+  }
+  y = 2;
+  return h;
+}
+`,
+		},
+		{
+			name: "top level",
+			source: `var x;
+print x;
+var y = 1;
+y = 2;
+print y;
+`,
+			want: `1:6-1:7: 'x' may be read before it is assigned
+2:4-2:5: value assigned to 'y' is never read
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			analyser.diagnostics = nil
+
+			for _, cfg := range BuildCFGs(statements) {
+				analyser.checkAssignments(cfg, resolver)
+			}
+
+			var builder strings.Builder
+			for _, diagnostic := range analyser.diagnostics {
+				fmt.Fprintf(&builder, "%d:%d-%d:%d: %s\n", diagnostic.Range.Start.Line, diagnostic.Range.Start.Character,
+					diagnostic.Range.End.Line, diagnostic.Range.End.Character, diagnostic.Message)
+			}
+			if got := builder.String(); got != test.want {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
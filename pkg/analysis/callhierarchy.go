@@ -0,0 +1,317 @@
+package analysis
+
+import "github.com/neet-007/lox_lsp_first/internal/lsp"
+
+// topLevelName names the code outside any function, which calls functions
+// like a function body does.
+const topLevelName = "<top level>"
+
+// callSite is a call whose callee is known: a function, a method, or a
+// class whose constructor is called. A nil caller is the top level. The
+// callee is declared in document, which need not be the file of the call.
+type callSite struct {
+	caller   *Function
+	callee   any
+	document *Document
+	name     Token
+	call     *Call
+}
+
+// globalLookup finds the function or class that another document than the
+// one given declares as a global name, as Analyser.globalOf does.
+type globalLookup func(name string, document *Document) (any, *Document, bool)
+
+// PrepareCallHierarchy returns the function, method or class named at
+// position.
+func (analyser *Analyser) PrepareCallHierarchy(id int, uri string, position lsp.Position) lsp.CallHierarchyPrepareResponse {
+	response := lsp.CallHierarchyPrepareResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.CallHierarchyItem{},
+	}
+
+	document, ok := analyser.documents[uri]
+	if !ok {
+		return response
+	}
+	token, ok := document.tokenAt(position)
+	if !ok {
+		return response
+	}
+	declaration, ok := document.declarationOf(token)
+	if !ok {
+		return response
+	}
+
+	if node, ok := document.callable(declaration.Token); ok {
+		response.Result = append(response.Result, document.callItem(node))
+	}
+
+	return response
+}
+
+// IncomingCalls returns the callers of item in every open or indexed file,
+// each with where it calls it.
+func (analyser *Analyser) IncomingCalls(id int, item lsp.CallHierarchyItem) lsp.CallHierarchyIncomingCallsResponse {
+	response := lsp.CallHierarchyIncomingCallsResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.CallHierarchyIncomingCall{},
+	}
+
+	document, ok := analyser.documents[item.URI]
+	if !ok {
+		return response
+	}
+	target, ok := document.itemNode(item.Kind, item.SelectionRange)
+	if !ok {
+		return response
+	}
+
+	// The top level of each file is a caller of its own.
+	type caller struct {
+		document *Document
+		function *Function
+	}
+	index := map[caller]int{}
+	for _, other := range analyser.workspace() {
+		for _, site := range other.callSites(analyser.globalOf) {
+			if site.callee != target {
+				continue
+			}
+			key := caller{other, site.caller}
+			i, ok := index[key]
+			if !ok {
+				i = len(response.Result)
+				index[key] = i
+				response.Result = append(response.Result, lsp.CallHierarchyIncomingCall{
+					From:       other.callerItem(site.caller),
+					FromRanges: []lsp.Range{},
+				})
+			}
+			response.Result[i].FromRanges = append(response.Result[i].FromRanges, tokenRange(site.name))
+		}
+	}
+
+	return response
+}
+
+// OutgoingCalls returns what item calls, each with where it is called, and
+// may be declared in another file. The calls of a class are those its
+// initializer makes.
+func (analyser *Analyser) OutgoingCalls(id int, item lsp.CallHierarchyItem) lsp.CallHierarchyOutgoingCallsResponse {
+	response := lsp.CallHierarchyOutgoingCallsResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.CallHierarchyOutgoingCall{},
+	}
+
+	document, ok := analyser.documents[item.URI]
+	if !ok {
+		return response
+	}
+	node, ok := document.itemNode(item.Kind, item.SelectionRange)
+	if !ok {
+		return response
+	}
+
+	var caller *Function
+	switch node := node.(type) {
+	case *Function:
+		caller = node
+	case *Class:
+		if _, caller = findMethod(node, "init", document.resolver); caller == nil {
+			return response
+		}
+	}
+
+	index := map[any]int{}
+	for _, site := range document.callSites(analyser.globalOf) {
+		if site.caller != caller {
+			continue
+		}
+		i, ok := index[site.callee]
+		if !ok {
+			i = len(response.Result)
+			index[site.callee] = i
+			response.Result = append(response.Result, lsp.CallHierarchyOutgoingCall{
+				To:         site.document.callItem(site.callee),
+				FromRanges: []lsp.Range{},
+			})
+		}
+		response.Result[i].FromRanges = append(response.Result[i].FromRanges, tokenRange(site.name))
+	}
+
+	return response
+}
+
+// callSites returns the calls of the document whose callee is known, in
+// source order. A call to a global the document does not declare is looked
+// up with globals, unless it is nil.
+func (document *Document) callSites(globals globalLookup) []callSite {
+	sites := []callSite{}
+
+	var caller *Function
+	var class *Class
+	var visit func(node any) bool
+	visit = func(node any) bool {
+		switch node := node.(type) {
+		case *Class:
+			enclosing := class
+			class = node
+			for _, method := range node.Methods {
+				Walk(method, visit)
+			}
+			class = enclosing
+			return false
+		case *Function:
+			enclosing := caller
+			caller = node
+			WalkStatements(node.Body, visit)
+			caller = enclosing
+			return false
+		case *Call:
+			if callee, declaring, name, ok := document.callee(node, class, globals); ok {
+				sites = append(sites, callSite{caller: caller, callee: callee, document: declaring, name: name, call: node})
+			}
+		}
+		return true
+	}
+	WalkStatements(document.Statements, visit)
+
+	return sites
+}
+
+// callee returns the function or class that call calls, the document that
+// declares it, and the name that call refers to it by.
+func (document *Document) callee(call *Call, class *Class, globals globalLookup) (any, *Document, Token, bool) {
+	resolver := document.resolver
+
+	var method *Function
+	var name Token
+	switch callee := call.Callee.(type) {
+	case *Variable:
+		symbol, ok := resolver.SymbolOf(callee)
+		if !ok {
+			if globals == nil {
+				return nil, nil, Token{}, false
+			}
+			node, declaring, ok := globals(callee.Name.Lexeme, document)
+			return node, declaring, callee.Name, ok
+		}
+		switch node := symbol.Node.(type) {
+		case *Function:
+			if symbol.Kind == FUNCTION_DECLARATION {
+				return node, document, callee.Name, true
+			}
+		case *Class:
+			if symbol.Kind == CLASS_DECLARATION {
+				return node, document, callee.Name, true
+			}
+		}
+		return nil, nil, Token{}, false
+	case *Get:
+		object := class
+		if _, ok := callee.Object.(*This); !ok {
+			object, _ = document.fields.ObjectClass(callee)
+		}
+		name = callee.Name
+		_, method = findMethod(object, callee.Name.Lexeme, resolver)
+	case *Super:
+		if class == nil {
+			return nil, nil, Token{}, false
+		}
+		name = callee.Method
+		_, method = findMethod(superclassOf(class, resolver), callee.Method.Lexeme, resolver)
+	}
+
+	return method, document, name, method != nil
+}
+
+// callable finds the function, method or class declared by name.
+func (document *Document) callable(name Token) (any, bool) {
+	var found any
+	WalkStatements(document.Statements, func(node any) bool {
+		if found != nil {
+			return false
+		}
+		switch node := node.(type) {
+		case *Function:
+			if node.Name == name {
+				found = node
+			}
+		case *Class:
+			if node.Name == name {
+				found = node
+			}
+		}
+		return true
+	})
+
+	return found, found != nil
+}
+
+// itemNode finds the declaration that an item of the document of kind
+// was made for from its selection range. The top level is a nil *Function,
+// the caller of the calls made outside any function.
+func (document *Document) itemNode(kind int, selection lsp.Range) (any, bool) {
+	if kind == lsp.SymbolKindFile {
+		return (*Function)(nil), true
+	}
+
+	for i := range document.Tokens {
+		if tokenRange(document.Tokens[i]) == selection {
+			return document.callable(document.Tokens[i])
+		}
+	}
+
+	return nil, false
+}
+
+func (document *Document) callerItem(caller *Function) lsp.CallHierarchyItem {
+	if caller == nil {
+		start, end := Token{}, Token{}
+		if len(document.Tokens) > 0 {
+			end = document.Tokens[len(document.Tokens)-1]
+		}
+		return lsp.CallHierarchyItem{
+			Name:           topLevelName,
+			Kind:           lsp.SymbolKindFile,
+			URI:            document.Uri,
+			Range:          Span{Start: start, End: end}.Range(),
+			SelectionRange: tokenRange(start),
+		}
+	}
+
+	return document.callItem(caller)
+}
+
+// callItem makes the item of a function, method or class.
+func (document *Document) callItem(node any) lsp.CallHierarchyItem {
+	var symbol lsp.DocumentSymbol
+	switch node := node.(type) {
+	case *Function:
+		kind := lsp.SymbolKindFunction
+		if declaration, ok := document.declared(node.Name); ok && declaration.Kind == METHOD_DECLARATION {
+			kind = lsp.SymbolKindMethod
+		}
+		symbol = document.symbol(node.Name, kind, Span{Start: node.Name, End: node.End}.Range())
+	case *Class:
+		symbol = document.symbol(node.Name, lsp.SymbolKindClass, Span{Start: node.Name, End: node.End}.Range())
+	}
+
+	return lsp.CallHierarchyItem{
+		Name:           symbol.Name,
+		Kind:           symbol.Kind,
+		Detail:         symbol.Detail,
+		URI:            document.Uri,
+		Range:          symbol.Range,
+		SelectionRange: symbol.SelectionRange,
+	}
+}
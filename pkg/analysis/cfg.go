@@ -0,0 +1,419 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type EdgeKind int
+
+const (
+	NORMAL_EDGE EdgeKind = iota
+	TRUE_EDGE
+	FALSE_EDGE
+)
+
+// BasicBlock is a run of nodes that are evaluated one after another. Nodes
+// are the statements of the block, each after the expressions it contains
+// that can be skipped: the operands of and/or. A block that branches ends
+// with its condition.
+type BasicBlock struct {
+	Index        int
+	Nodes        []any
+	Successors   []*Edge
+	Predecessors []*Edge
+}
+
+type Edge struct {
+	From *BasicBlock
+	To   *BasicBlock
+	Kind EdgeKind
+}
+
+// CFG is the control-flow graph of a function body or of the top level of
+// a program. Every path ends in Exit, and a return in a function jumps
+// there directly. The code after a return, or behind a condition that is a
+// literal, ends up in blocks that no path from Entry reaches.
+type CFG struct {
+	Name     string
+	Function *Function
+	Entry    *BasicBlock
+	Exit     *BasicBlock
+	Blocks   []*BasicBlock
+}
+
+// BuildCFG builds the graph of the top level statements of a program.
+// Function and class declarations are nodes of it; their bodies have
+// graphs of their own.
+func BuildCFG(statements []Stmt) *CFG {
+	return newCFGBuilder("<top level>", nil).build(statements)
+}
+
+// BuildFunctionCFG builds the graph of the body of function.
+func BuildFunctionCFG(name string, function *Function) *CFG {
+	return newCFGBuilder(name, function).build(function.Body)
+}
+
+// BuildCFGs builds the graph of the top level of a program followed by the
+// graphs of every function and method in it, in source order.
+func BuildCFGs(statements []Stmt) []*CFG {
+	cfgs := []*CFG{BuildCFG(statements)}
+
+	var class *Class
+	var visit func(node any) bool
+	visit = func(node any) bool {
+		switch node := node.(type) {
+		case *Class:
+			enclosing := class
+			class = node
+			for _, method := range node.Methods {
+				Walk(method, visit)
+			}
+			class = enclosing
+			return false
+		case *Function:
+			name := node.Name.Lexeme
+			if class != nil && isMethodOf(node, class) {
+				name = class.Name.Lexeme + "." + name
+			}
+			cfgs = append(cfgs, BuildFunctionCFG(name, node))
+		}
+		return true
+	}
+	WalkStatements(statements, visit)
+
+	return cfgs
+}
+
+func isMethodOf(function *Function, class *Class) bool {
+	for _, method := range class.Methods {
+		if method == function {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reachable returns the blocks that some path from Entry goes through.
+func (cfg *CFG) Reachable() map[*BasicBlock]bool {
+	reachable := map[*BasicBlock]bool{}
+	var visit func(block *BasicBlock)
+	visit = func(block *BasicBlock) {
+		if reachable[block] {
+			return
+		}
+		reachable[block] = true
+		for _, edge := range block.Successors {
+			visit(edge.To)
+		}
+	}
+	visit(cfg.Entry)
+
+	return reachable
+}
+
+// FallsOff reports whether a path reaches Exit other than by a return: the
+// end of the body of a function, where it returns nil.
+func (cfg *CFG) FallsOff() bool {
+	reachable := cfg.Reachable()
+	for _, edge := range cfg.Exit.Predecessors {
+		if _, ok := edge.From.last().(*Return); !ok && reachable[edge.From] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// last returns the node block ends with, or nil when it is empty.
+func (block *BasicBlock) last() any {
+	if len(block.Nodes) == 0 {
+		return nil
+	}
+
+	return block.Nodes[len(block.Nodes)-1]
+}
+
+type cfgBuilder struct {
+	cfg     *CFG
+	current *BasicBlock
+}
+
+func newCFGBuilder(name string, function *Function) *cfgBuilder {
+	builder := &cfgBuilder{cfg: &CFG{Name: name, Function: function, Blocks: []*BasicBlock{}}}
+	builder.cfg.Entry = builder.newBlock()
+	builder.cfg.Exit = builder.newBlock()
+
+	return builder
+}
+
+func (builder *cfgBuilder) build(statements []Stmt) *CFG {
+	builder.current = builder.newBlock()
+	builder.connect(builder.cfg.Entry, builder.current, NORMAL_EDGE)
+
+	builder.statements(statements)
+	builder.connect(builder.current, builder.cfg.Exit, NORMAL_EDGE)
+
+	return builder.cfg
+}
+
+func (builder *cfgBuilder) newBlock() *BasicBlock {
+	block := &BasicBlock{
+		Index:        len(builder.cfg.Blocks),
+		Nodes:        []any{},
+		Successors:   []*Edge{},
+		Predecessors: []*Edge{},
+	}
+	builder.cfg.Blocks = append(builder.cfg.Blocks, block)
+
+	return block
+}
+
+func (builder *cfgBuilder) connect(from *BasicBlock, to *BasicBlock, kind EdgeKind) {
+	edge := &Edge{From: from, To: to, Kind: kind}
+	from.Successors = append(from.Successors, edge)
+	to.Predecessors = append(to.Predecessors, edge)
+}
+
+func (builder *cfgBuilder) add(node any) {
+	builder.current.Nodes = append(builder.current.Nodes, node)
+}
+
+func (builder *cfgBuilder) statements(statements []Stmt) {
+	for _, stmt := range statements {
+		builder.statement(stmt)
+	}
+}
+
+func (builder *cfgBuilder) statement(stmt Stmt) {
+	switch stmt := stmt.(type) {
+	case *Block:
+		builder.statements(stmt.Statements)
+	case *Expression:
+		builder.expression(stmt.Expression)
+		builder.add(stmt)
+	case *Print:
+		builder.expression(stmt.Expression)
+		builder.add(stmt)
+	case *Var:
+		builder.expression(stmt.Initializer)
+		builder.add(stmt)
+	case *Return:
+		builder.expression(stmt.Value)
+		builder.add(stmt)
+
+		// A return outside a function is an error, not the end of the
+		// program.
+		if builder.cfg.Function != nil {
+			builder.connect(builder.current, builder.cfg.Exit, NORMAL_EDGE)
+			builder.current = builder.newBlock()
+		}
+	case *If:
+		then := builder.newBlock()
+		after := builder.newBlock()
+		otherwise := after
+		if stmt.ElseBranch != nil {
+			otherwise = builder.newBlock()
+		}
+
+		builder.condition(stmt.Condition, then, otherwise)
+
+		builder.current = then
+		builder.statement(stmt.ThenBranch)
+		builder.connect(builder.current, after, NORMAL_EDGE)
+
+		if stmt.ElseBranch != nil {
+			builder.current = otherwise
+			builder.statement(stmt.ElseBranch)
+			builder.connect(builder.current, after, NORMAL_EDGE)
+		}
+		builder.current = after
+	case *While:
+		header := builder.newBlock()
+		body := builder.newBlock()
+		after := builder.newBlock()
+		builder.connect(builder.current, header, NORMAL_EDGE)
+
+		builder.current = header
+		builder.condition(stmt.Condition, body, after)
+
+		builder.current = body
+		builder.statement(stmt.Body)
+		builder.connect(builder.current, header, NORMAL_EDGE)
+		builder.current = after
+	case *Function, *Class:
+		builder.add(stmt)
+	}
+}
+
+// expression splits the current block where an and/or in expr may skip
+// its right operand.
+func (builder *cfgBuilder) expression(expr Expr) {
+	if expr == nil {
+		return
+	}
+
+	logical, ok := expr.(*Logical)
+	if !ok {
+		for _, child := range children(expr) {
+			builder.expression(child)
+		}
+		return
+	}
+
+	builder.expression(logical.Left)
+	builder.add(logical.Left)
+
+	right := builder.newBlock()
+	after := builder.newBlock()
+	if logical.Operator.Type == OR {
+		builder.connect(builder.current, after, TRUE_EDGE)
+		builder.connect(builder.current, right, FALSE_EDGE)
+	} else {
+		builder.connect(builder.current, right, TRUE_EDGE)
+		builder.connect(builder.current, after, FALSE_EDGE)
+	}
+
+	builder.current = right
+	builder.expression(logical.Right)
+	builder.add(logical.Right)
+	builder.connect(builder.current, after, NORMAL_EDGE)
+	builder.current = after
+}
+
+// condition branches from the current block to then when expr is truthy
+// and to otherwise when it is not. The operands of and/or branch on their
+// own, and a literal only goes one way.
+func (builder *cfgBuilder) condition(expr Expr, then *BasicBlock, otherwise *BasicBlock) {
+	switch expr := expr.(type) {
+	case *Grouping:
+		builder.condition(expr.Expression, then, otherwise)
+		return
+	case *Logical:
+		right := builder.newBlock()
+		if expr.Operator.Type == OR {
+			builder.condition(expr.Left, then, right)
+		} else {
+			builder.condition(expr.Left, right, otherwise)
+		}
+		builder.current = right
+		builder.condition(expr.Right, then, otherwise)
+		return
+	}
+
+	builder.expression(expr)
+	builder.add(expr)
+
+	if literal, ok := expr.(*Literal); ok {
+		if isTruthy(literal.Value) {
+			builder.connect(builder.current, then, NORMAL_EDGE)
+		} else {
+			builder.connect(builder.current, otherwise, NORMAL_EDGE)
+		}
+		return
+	}
+
+	builder.connect(builder.current, then, TRUE_EDGE)
+	builder.connect(builder.current, otherwise, FALSE_EDGE)
+}
+
+func isTruthy(value any) bool {
+	if value == nil {
+		return false
+	}
+	if boolean, ok := value.(bool); ok {
+		return boolean
+	}
+
+	return true
+}
+
+// children returns the operands of expr in the order they are evaluated.
+func children(expr Expr) []Expr {
+	switch expr := expr.(type) {
+	case *Assign:
+		return []Expr{expr.Value}
+	case *Binary:
+		return []Expr{expr.Left, expr.Right}
+	case *Call:
+		return append([]Expr{expr.Callee}, expr.Arguments...)
+	case *Get:
+		return []Expr{expr.Object}
+	case *Grouping:
+		return []Expr{expr.Expression}
+	case *Set:
+		return []Expr{expr.Object, expr.Value}
+	case *Unary:
+		return []Expr{expr.Right}
+	}
+
+	return nil
+}
+
+// WriteDOT writes the graphs as one Graphviz digraph with a cluster for
+// each graph.
+func WriteDOT(writer io.Writer, cfgs []*CFG) error {
+	var builder strings.Builder
+	builder.WriteString("digraph cfg {\n  node [shape=box fontname=monospace];\n")
+
+	for i, cfg := range cfgs {
+		fmt.Fprintf(&builder, "  subgraph cluster_%d {\n    label=%s;\n", i, dotString(cfg.Name))
+		for _, block := range cfg.Blocks {
+			fmt.Fprintf(&builder, "    g%db%d [label=%s];\n", i, block.Index, dotString(blockLabel(cfg, block)))
+		}
+		for _, block := range cfg.Blocks {
+			for _, edge := range block.Successors {
+				fmt.Fprintf(&builder, "    g%db%d -> g%db%d", i, edge.From.Index, i, edge.To.Index)
+				switch edge.Kind {
+				case TRUE_EDGE:
+					builder.WriteString(" [label=true]")
+				case FALSE_EDGE:
+					builder.WriteString(" [label=false]")
+				}
+				builder.WriteString(";\n")
+			}
+		}
+		builder.WriteString("  }\n")
+	}
+	builder.WriteString("}\n")
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func blockLabel(cfg *CFG, block *BasicBlock) string {
+	lines := []string{}
+	switch block {
+	case cfg.Entry:
+		lines = append(lines, "entry")
+	case cfg.Exit:
+		lines = append(lines, "exit")
+	default:
+		lines = append(lines, fmt.Sprintf("b%d", block.Index))
+	}
+
+	printer := NewAstPrinter()
+	for _, node := range block.Nodes {
+		switch node := node.(type) {
+		case *Function:
+			lines = append(lines, fmt.Sprintf("(fun %s)", node.Name.Lexeme))
+		case *Class:
+			lines = append(lines, fmt.Sprintf("(class %s)", node.Name.Lexeme))
+		case Stmt:
+			lines = append(lines, printer.print(node))
+		case Expr:
+			lines = append(lines, fmt.Sprint(node.Accept(&printer)))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func dotString(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	text = strings.ReplaceAll(text, `"`, `\"`)
+	text = strings.ReplaceAll(text, "\n", `\l`)
+	return `"` + text + `"`
+}
@@ -0,0 +1,194 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var edgeKindNames = map[EdgeKind]string{
+	NORMAL_EDGE: "",
+	TRUE_EDGE:   " true",
+	FALSE_EDGE:  " false",
+}
+
+// formatCFG lists the blocks of a graph with their nodes and successors,
+// marking the ones no path reaches.
+func formatCFG(cfg *CFG) string {
+	reachable := cfg.Reachable()
+	printer := NewAstPrinter()
+
+	var builder strings.Builder
+	for _, block := range cfg.Blocks {
+		nodes := []string{}
+		for _, node := range block.Nodes {
+			switch node := node.(type) {
+			case Stmt:
+				nodes = append(nodes, printer.print(node))
+			case Expr:
+				nodes = append(nodes, fmt.Sprint(node.Accept(&printer)))
+			}
+		}
+		successors := []string{}
+		for _, edge := range block.Successors {
+			successors = append(successors, fmt.Sprintf("b%d%s", edge.To.Index, edgeKindNames[edge.Kind]))
+		}
+
+		unreachable := ""
+		if !reachable[block] {
+			unreachable = " unreachable"
+		}
+		fmt.Fprintf(&builder, "b%d%s [%s]", block.Index, unreachable, strings.Join(nodes, " "))
+		if len(successors) > 0 {
+			fmt.Fprintf(&builder, " -> %s", strings.Join(successors, ", "))
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+func TestCFG(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "if else",
+			source: `if (a) print 1; else print 2;
+print 3;
+`,
+			want: `b0 [] -> b2
+b1 []
+b2 [a] -> b3 true, b5 false
+b3 [(print (value 1))] -> b4
+b4 [(print (value 3))] -> b1
+b5 [(print (value 2))] -> b4
+`,
+		},
+		{
+			name: "while",
+			source: `var i = 0;
+while (i < 3) i = i + 1;
+`,
+			want: `b0 [] -> b2
+b1 []
+b2 [(var i (initializer 0))] -> b3
+b3 [(< i 3)] -> b4 true, b5 false
+b4 [(expression (assign i (+ i 1)))] -> b3
+b5 [] -> b1
+`,
+		},
+		{
+			name:   "for is a while in a block",
+			source: "for (var i = 0; i < 2; i = i + 1) print i;\n",
+			want: `b0 [] -> b2
+b1 []
+b2 [(var i (initializer 0))] -> b3
+b3 [(< i 2)] -> b4 true, b5 false
+b4 [(print (value i)) (expression (assign i (+ i 1)))] -> b3
+b5 [] -> b1
+`,
+		},
+		{
+			name:   "short circuit in a condition",
+			source: "if (a and b or c) print 1;\n",
+			want: `b0 [] -> b2
+b1 []
+b2 [a] -> b6 true, b5 false
+b3 [(print (value 1))] -> b4
+b4 [] -> b1
+b5 [c] -> b3 true, b4 false
+b6 [b] -> b3 true, b5 false
+`,
+		},
+		{
+			name:   "short circuit in an expression",
+			source: "print a or f();\n",
+			want: `b0 [] -> b2
+b1 []
+b2 [a] -> b4 true, b3 false
+b3 [(call f)] -> b4
+b4 [(print (value (or a (call f))))] -> b1
+`,
+		},
+		{
+			name: "literal conditions",
+			source: `if (false) print 1;
+while (true) print 2;
+print 3;
+`,
+			want: `b0 [] -> b2
+b1 unreachable []
+b2 [false] -> b4
+b3 unreachable [(print (value 1))] -> b4
+b4 [] -> b5
+b5 [true] -> b6
+b6 [(print (value 2))] -> b5
+b7 unreachable [(print (value 3))] -> b1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, _, _ := resolve(t, test.source)
+			if got := formatCFG(BuildCFG(statements)); got != test.want {
+				t.Errorf("cfg:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFunctionCFGs(t *testing.T) {
+	source := `fun sign(n) {
+  if (n < 0) return -1;
+  return 1;
+  print "never";
+}
+class Counter {
+  count() {
+    fun inner() {}
+  }
+}
+`
+	statements, _, _ := resolve(t, source)
+	cfgs := BuildCFGs(statements)
+
+	names := []string{}
+	for _, cfg := range cfgs {
+		names = append(names, cfg.Name)
+	}
+	if got, want := strings.Join(names, " "), "<top level> sign Counter.count inner"; got != want {
+		t.Fatalf("graphs %q, want %q", got, want)
+	}
+
+	want := `b0 [] -> b2
+b1 []
+b2 [(< n 0)] -> b3 true, b4 false
+b3 [(return (value (- 1)))] -> b1
+b4 [(return (value 1))] -> b1
+b5 unreachable [] -> b4
+b6 unreachable [(print (value never))] -> b1
+`
+	if got := formatCFG(cfgs[1]); got != want {
+		t.Errorf("cfg of sign:\n%s\nwant:\n%s", got, want)
+	}
+
+	var dot bytes.Buffer
+	if err := WriteDOT(&dot, cfgs); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`subgraph cluster_1 {`,
+		`label="sign";`,
+		`g1b2 -> g1b3 [label=true];`,
+		`g1b6 [label="b6\l(print (value never))\l"];`,
+	} {
+		if !strings.Contains(dot.String(), line) {
+			t.Errorf("dot output has no line %q:\n%s", line, dot.String())
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// quickFix is an edit that resolves the diagnostic at index diagnostic of
+// the analysis it was offered in.
+type quickFix struct {
+	diagnostic int
+	title      string
+	edits      []lsp.TextEdit
+}
+
+// CodeActions returns the quick fixes whose diagnostics or edits are within
+// range_, and the refactorings of the code there. When only is not empty,
+// just the actions of the kinds it lists are returned.
+func (analyser *Analyser) CodeActions(id int, uri string, range_ lsp.Range, only []string) lsp.CodeActionResponse {
+	response := lsp.CodeActionResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.CodeAction{},
+	}
+
+	document, ok := analyser.documents[uri]
+	if !ok {
+		return response
+	}
+
+	for _, action := range document.fixes {
+		if wantedKind(action.Kind, only) && fixesWithin(action, uri, range_) {
+			response.Result = append(response.Result, action)
+		}
+	}
+	for _, action := range document.refactors(range_) {
+		if wantedKind(action.Kind, only) {
+			response.Result = append(response.Result, action)
+		}
+	}
+
+	return response
+}
+
+// fixesWithin reports whether action fixes a diagnostic within range_, or
+// edits there: a missing ';' is reported at the token after it.
+func fixesWithin(action lsp.CodeAction, uri string, range_ lsp.Range) bool {
+	if overlaps(action.Diagnostics[0].Range, range_) {
+		return true
+	}
+	for _, edit := range action.Edit.Changes[uri] {
+		if overlaps(edit.Range, range_) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wantedKind reports whether a code action of kind was asked for: only
+// lists kinds like "refactor" that cover their subkinds too.
+func wantedKind(kind string, only []string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, wanted := range only {
+		if kind == wanted || strings.HasPrefix(kind, wanted+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func overlaps(a lsp.Range, b lsp.Range) bool {
+	return !positionBefore(a.End, b.Start) && !positionBefore(b.End, a.Start)
+}
+
+// fix offers edits as a quick fix for diagnostic, which must be the one
+// reported last.
+func (analyser *Analyser) fix(diagnostic *lsp.Diagnostic, title string, edits ...lsp.TextEdit) {
+	for i := len(analyser.diagnostics) - 1; i >= 0; i-- {
+		if &analyser.diagnostics[i] == diagnostic {
+			analyser.fixes = append(analyser.fixes, quickFix{diagnostic: i, title: title, edits: edits})
+			return
+		}
+	}
+}
+
+// quickFixes makes the code actions of the fixes offered, once nothing
+// more is added to their diagnostics.
+func (analyser *Analyser) quickFixes() []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, fix := range analyser.fixes {
+		actions = append(actions, lsp.CodeAction{
+			Title:       fix.title,
+			Kind:        lsp.CodeActionKindQuickFix,
+			Diagnostics: []lsp.Diagnostic{analyser.diagnostics[fix.diagnostic]},
+			Edit: &lsp.WorkspaceEdit{
+				Changes: map[string][]lsp.TextEdit{analyser.uri: fix.edits},
+			},
+		})
+	}
+
+	return actions
+}
+
+func insertText(position lsp.Position, text string) lsp.TextEdit {
+	return lsp.TextEdit{Range: lsp.Range{Start: position, End: position}, NewText: text}
+}
+
+// declareBefore inserts a declaration of name on a line of its own before
+// the statement of statements that contains position, indented like it.
+func (analyser *Analyser) declareBefore(statements []Stmt, position lsp.Position, name string) (lsp.TextEdit, bool) {
+	stmt, ok := statementAt(statements, position, analyser.spans)
+	if !ok {
+		return lsp.TextEdit{}, false
+	}
+
+	span, _ := statementSpan(stmt, analyser.spans)
+	line := span.Start.StartLine
+	return insertText(lsp.Position{Line: line}, indentation(analyser.source, line)+"var "+name+";\n"), true
+}
+
+// statementAt returns the innermost statement written in a block or body
+// whose span contains position. Statements the parser made up within a
+// desugared for loop are looked through.
+func statementAt(statements []Stmt, position lsp.Position, spans map[any]Span) (Stmt, bool) {
+	for _, stmt := range statements {
+		span, ok := statementSpan(stmt, spans)
+		if ok && !contains(span.Range(), position) {
+			continue
+		}
+
+		for _, inner := range innerStatements(stmt) {
+			if found, ok := statementAt(inner, position, spans); ok {
+				return found, true
+			}
+		}
+		if ok {
+			return stmt, true
+		}
+	}
+
+	return nil, false
+}
+
+// statementSpan returns where stmt was written. The block a for loop is
+// desugared into spans the whole loop.
+func statementSpan(stmt Stmt, spans map[any]Span) (Span, bool) {
+	if span, ok := spans[stmt]; ok {
+		return span, true
+	}
+	if block, ok := stmt.(*Block); ok && block.Start.Lexeme != "" {
+		return Span{Start: block.Start, End: block.End}, true
+	}
+
+	return Span{}, false
+}
+
+// innerStatements returns the blocks and bodies directly within stmt.
+func innerStatements(stmt Stmt) [][]Stmt {
+	switch stmt := stmt.(type) {
+	case *Block:
+		return [][]Stmt{stmt.Statements}
+	case *Function:
+		return [][]Stmt{stmt.Body}
+	case *Class:
+		bodies := [][]Stmt{}
+		for _, method := range stmt.Methods {
+			bodies = append(bodies, method.Body)
+		}
+		return bodies
+	case *If:
+		return append(innerStatements(stmt.ThenBranch), innerStatements(stmt.ElseBranch)...)
+	case *While:
+		return innerStatements(stmt.Body)
+	}
+
+	return nil
+}
+
+func contains(range_ lsp.Range, position lsp.Position) bool {
+	return !positionBefore(position, range_.Start) && !positionBefore(range_.End, position)
+}
+
+// removal is the range to delete to remove span from source: the lines it
+// is on when nothing else is written there.
+func removal(source []byte, span Span) lsp.Range {
+	range_ := span.Range()
+	lines := bytes.Split(source, []byte("\n"))
+	if range_.End.Line >= len(lines) {
+		return range_
+	}
+
+	before := lines[range_.Start.Line][:min(range_.Start.Character, len(lines[range_.Start.Line]))]
+	after := lines[range_.End.Line][min(range_.End.Character, len(lines[range_.End.Line])):]
+	if len(bytes.TrimSpace(before)) > 0 || len(bytes.TrimSpace(after)) > 0 {
+		return range_
+	}
+
+	return lsp.Range{
+		Start: lsp.Position{Line: range_.Start.Line},
+		End:   lsp.Position{Line: range_.End.Line + 1},
+	}
+}
+
+// indentation returns the white space that line of source starts with.
+func indentation(source []byte, line int) string {
+	lines := bytes.Split(source, []byte("\n"))
+	if line >= len(lines) {
+		return ""
+	}
+
+	text := lines[line]
+	return string(text[:len(text)-len(bytes.TrimLeft(text, " \t"))])
+}
+
+// sideEffectFree reports whether evaluating expr changes nothing: it calls
+// nothing and assigns nothing.
+func sideEffectFree(expr Expr) bool {
+	free := true
+	Walk(expr, func(node any) bool {
+		switch node.(type) {
+		case *Call, *Assign, *Set:
+			free = false
+		}
+		return free
+	})
+
+	return free
+}
@@ -0,0 +1,369 @@
+package analysis
+
+import (
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+func TestQuickFixes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		title  string
+		fixed  string
+	}{
+		{
+			name:   "missing semicolon",
+			source: "var a = 1\nprint a;\n",
+			title:  "Insert ';'",
+			fixed:  "var a = 1;\nprint a;\n",
+		},
+		{
+			name:   "missing paren",
+			source: "fun f(a) { print a; }\nf(1;\n",
+			title:  "Insert ')'",
+			fixed:  "fun f(a) { print a; }\nf(1);\n",
+		},
+		{
+			name:   "misspelled name",
+			source: "var count = 1;\nprint cout;\n",
+			title:  "Change to 'count'",
+			fixed:  "var count = 1;\nprint count;\n",
+		},
+		{
+			name:   "misspelled superclass",
+			source: "class Shape {}\nclass Square < Shap {}\nSquare();\n",
+			title:  "Change to 'Shape'",
+			fixed:  "class Shape {}\nclass Square < Shape {}\nSquare();\n",
+		},
+		{
+			name: "declare undefined variable",
+			source: `fun f() {
+  if (true) {
+    print total +
+      1;
+  }
+}
+`,
+			title: "Declare 'total'",
+			fixed: `fun f() {
+  if (true) {
+    var total;
+    print total +
+      1;
+  }
+}
+`,
+		},
+		{
+			name:   "declare before a for loop",
+			source: "for (var i = 0; i < limit; i = i + 1) print i;\n",
+			title:  "Declare 'limit'",
+			fixed:  "var limit;\nfor (var i = 0; i < limit; i = i + 1) print i;\n",
+		},
+		{
+			name:   "remove unused variable",
+			source: "{\n  var a = 1 + 2;\n  print 3;\n}\n",
+			title:  "Remove unused variable 'a'",
+			fixed:  "{\n  print 3;\n}\n",
+		},
+		{
+			name:   "remove unused variable keeping its initializer",
+			source: "fun f() { return 1; }\n{\n  var a = f();\n}\n",
+			title:  "Remove unused variable 'a'",
+			fixed:  "fun f() { return 1; }\n{\n  f();\n}\n",
+		},
+		{
+			name:   "return value in init",
+			source: "class A {\n  init() {\n    return this.x;\n  }\n}\nA();\n",
+			title:  "Remove the return value",
+			fixed:  "class A {\n  init() {\n    return;\n  }\n}\nA();\n",
+		},
+		{
+			name:   "missing init parameter",
+			source: "class P {\n  init(x) {\n    this.x = x;\n  }\n}\nvar y = 2;\nP(1, y, 3);\n",
+			title:  "Add parameters 'y', 'arg3' to P.init",
+			fixed:  "class P {\n  init(x, y, arg3) {\n    this.x = x;\n  }\n}\nvar y = 2;\nP(1, y, 3);\n",
+		},
+		{
+			name:   "init without parameters",
+			source: "class P {\n  init() {}\n}\nP(1);\n",
+			title:  "Add parameter 'arg1' to P.init",
+			fixed:  "class P {\n  init(arg1) {}\n}\nP(1);\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uri := "file:///fix.lox"
+			analyser := NewAnaylser()
+			analyser.Analyse([]byte(test.source), uri, log.New(io.Discard, "", 0))
+
+			everything := lsp.Range{End: lsp.Position{Line: 100}}
+			titles := []string{}
+			for _, action := range analyser.CodeActions(1, uri, everything, nil).Result {
+				if action.Title != test.title {
+					titles = append(titles, action.Title)
+					continue
+				}
+				if got := applyEdits(test.source, action.Edit.Changes[uri]); got != test.fixed {
+					t.Errorf("fixed:\n%s\nwant:\n%s", got, test.fixed)
+				}
+				return
+			}
+			t.Errorf("no fix %q among %q", test.title, titles)
+		})
+	}
+}
+
+// applyEdits applies edits to source, the later ones first.
+func applyEdits(source string, edits []lsp.TextEdit) string {
+	offset := func(position lsp.Position) int {
+		lines := strings.SplitAfter(source, "\n")
+		at := 0
+		for _, line := range lines[:min(position.Line, len(lines))] {
+			at += len(line)
+		}
+		return min(at+position.Character, len(source))
+	}
+
+	edits = append([]lsp.TextEdit{}, edits...)
+	sort.Slice(edits, func(i, j int) bool {
+		return positionBefore(edits[j].Range.Start, edits[i].Range.Start)
+	})
+	for _, edit := range edits {
+		source = source[:offset(edit.Range.Start)] + edit.NewText + source[offset(edit.Range.End):]
+	}
+
+	return source
+}
+
+func TestRefactors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		title  string
+		want   string
+	}{
+		{
+			name:   "extract variable",
+			source: "fun f(a, b) {\n  print [[a * b]] + 1;\n}\nf(1, 2);\n",
+			title:  "Extract variable",
+			want:   "fun f(a, b) {\n  var value = a * b;\n  print value + 1;\n}\nf(1, 2);\n",
+		},
+		{
+			name:   "extract variable with a fresh name",
+			source: "var value = 2;\nprint [[ value + 1 ]];\n",
+			title:  "Extract variable",
+			want:   "var value = 2;\nvar value2 = value + 1;\nprint  value2 ;\n",
+		},
+		{
+			name:   "extract variable on the line of the function",
+			source: "fun f(a) { return [[a * 2]]; }\nprint f(1);\n",
+			title:  "Extract variable",
+			want:   "fun f(a) { var value = a * 2; return value; }\nprint f(1);\n",
+		},
+		{
+			name:   "extract variable from an if condition",
+			source: "var a = 1;\nif ([[a > 1]]) print a;\n",
+			title:  "Extract variable",
+			want:   "var a = 1;\nvar value = a > 1;\nif (value) print a;\n",
+		},
+		{
+			name:   "no extracting from a for loop initializer",
+			source: "for (var i = [[1 + 1]]; i < 3; i = i + 1) print i;\n",
+			title:  "Extract variable",
+		},
+		{
+			name:   "no extracting from an if branch without braces",
+			source: "var b = nil;\nif (b != nil) print [[b.x]];\n",
+			title:  "Extract variable",
+		},
+		{
+			name:   "no extracting from an else if condition",
+			source: "var a = 1;\nif (a < 0) print a;\nelse if ([[a > 1]]) print a;\n",
+			title:  "Extract variable",
+		},
+		{
+			name:   "no extracting from a loop condition",
+			source: "var i = 0;\nwhile ([[i < 3]]) i = i + 1;\n",
+			title:  "Extract variable",
+		},
+		{
+			name:   "no extracting a short circuited operand",
+			source: "var a = true;\nprint a and [[!a]];\n",
+			title:  "Extract variable",
+		},
+		{
+			name: "extract function",
+			source: `fun report(items, scale) {
+  var total = 0;
+  [[var doubled = items * scale;
+  print doubled;]]
+  return total;
+}
+report(1, 2);
+`,
+			title: "Extract function",
+			want: `fun extracted(items, scale) {
+  var doubled = items * scale;
+  print doubled;
+}
+
+fun report(items, scale) {
+  var total = 0;
+  extracted(items, scale);
+  return total;
+}
+report(1, 2);
+`,
+		},
+		{
+			name: "extract function returning the modified variable",
+			source: `fun sum(n) {
+  var total = 0;
+  [[for (var i = 0; i < n; i = i + 1) {
+    total = total + i;
+  }]]
+  return total;
+}
+print sum(3);
+`,
+			title: "Extract function",
+			want: `fun extracted(n, total) {
+  for (var i = 0; i < n; i = i + 1) {
+    total = total + i;
+  }
+  return total;
+}
+
+fun sum(n) {
+  var total = 0;
+  total = extracted(n, total);
+  return total;
+}
+print sum(3);
+`,
+		},
+		{
+			name:   "extract function returning a declared variable",
+			source: "[[var a = 1;\nvar b = a + 1;]]\nprint b;\n",
+			title:  "Extract function",
+			want:   "fun extracted() {\n  var a = 1;\n  var b = a + 1;\n  return b;\n}\n\nvar b = extracted();\nprint b;\n",
+		},
+		{
+			name:   "no extracting a return",
+			source: "fun f(a) {\n  [[print a;\n  return a;]]\n}\nf(1);\n",
+			title:  "Extract function",
+		},
+		{
+			name:   "no extracting two modified variables",
+			source: "fun f(a, b) {\n  [[a = 1;\n  b = 2;]]\n  print a + b;\n}\nf(1, 2);\n",
+			title:  "Extract function",
+		},
+		{
+			name:   "inline variable",
+			source: "fun f(a) {\n  var [[]]twice = a * 2;\n  print twice;\n  print -twice;\n}\nf(1);\n",
+			title:  "Inline variable 'twice'",
+			want:   "fun f(a) {\n  print a * 2;\n  print -(a * 2);\n}\nf(1);\n",
+		},
+		{
+			name:   "inline variable from a use",
+			source: "var greeting = \"hi\";\nprint [[]]greeting;\n",
+			title:  "Inline variable 'greeting'",
+			want:   "print \"hi\";\n",
+		},
+		{
+			name:   "no inlining a call",
+			source: "fun f() { return 1; }\nvar [[]]a = f();\nprint a;\n",
+			title:  "Inline variable 'a'",
+		},
+		{
+			name:   "no inlining what is assigned later",
+			source: "var b = 1;\nvar [[]]a = b;\nb = 2;\nprint a;\n",
+			title:  "Inline variable 'a'",
+		},
+		{
+			name:   "no inlining what is declared again later",
+			source: "var b = 1;\nvar [[]]a = b;\nvar b = 2;\nprint a;\n",
+			title:  "Inline variable 'a'",
+		},
+		{
+			name:   "no inlining a variable declared again",
+			source: "var [[]]a = 1;\nprint a;\nvar a = 2;\nprint a;\n",
+			title:  "Inline variable 'a'",
+		},
+		{
+			name:   "no inlining where a name is shadowed",
+			source: "var b = 1;\nvar [[]]a = b;\n{\n  var b = 2;\n  print a;\n}\n",
+			title:  "Inline variable 'a'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := strings.Index(test.source, "[[")
+			end := strings.Index(test.source, "]]") - 2
+			source := strings.Replace(strings.Replace(test.source, "[[", "", 1), "]]", "", 1)
+			position := func(offset int) lsp.Position {
+				before := source[:offset]
+				return lsp.Position{
+					Line:      strings.Count(before, "\n"),
+					Character: len(before) - strings.LastIndex(before, "\n") - 1,
+				}
+			}
+
+			uri := "file:///refactor.lox"
+			analyser := NewAnaylser()
+			analyser.Analyse([]byte(source), uri, log.New(io.Discard, "", 0))
+
+			selection := lsp.Range{Start: position(start), End: position(end)}
+			for _, action := range analyser.CodeActions(1, uri, selection, []string{"refactor"}).Result {
+				if action.Title != test.title {
+					continue
+				}
+				if test.want == "" {
+					t.Fatalf("got %q, want none", action.Title)
+				}
+				if got := applyEdits(source, action.Edit.Changes[uri]); got != test.want {
+					t.Errorf("refactored:\n%s\nwant:\n%s", got, test.want)
+				}
+				return
+			}
+			if test.want != "" {
+				t.Errorf("no refactoring %q", test.title)
+			}
+		})
+	}
+}
+
+func TestOffset(t *testing.T) {
+	analyser := NewAnaylser()
+	analyser.Analyse([]byte("var a = 1;\nprint a;\n"), "file:///offset.lox", log.New(io.Discard, "", 0))
+	document := analyser.documents["file:///offset.lox"]
+
+	tests := []struct {
+		position lsp.Position
+		want     int
+	}{
+		{lsp.Position{Line: 1, Character: 6}, 17},
+		{lsp.Position{Line: 0, Character: -4}, 0},
+		{lsp.Position{Line: -1, Character: 3}, 3},
+		{lsp.Position{Line: 1, Character: -20}, 0},
+		{lsp.Position{Line: 5, Character: 0}, 20},
+	}
+	for _, test := range tests {
+		if got := document.offset(test.position); got != test.want {
+			t.Errorf("offset(%v) = %d, want %d", test.position, got, test.want)
+		}
+	}
+
+	// A selection from before the start must not slice before it.
+	selection := lsp.Range{Start: lsp.Position{Line: -1, Character: -1}, End: lsp.Position{Line: 0, Character: 9}}
+	analyser.CodeActions(1, "file:///offset.lox", selection, []string{"refactor"})
+}
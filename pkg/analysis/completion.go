@@ -0,0 +1,133 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+var declarationCompletionKinds = map[DeclarationKind]int{
+	VARIABLE_DECLARATION:  lsp.CompletionItemKindVariable,
+	PARAMETER_DECLARATION: lsp.CompletionItemKindVariable,
+	FUNCTION_DECLARATION:  lsp.CompletionItemKindFunction,
+	CLASS_DECLARATION:     lsp.CompletionItemKindClass,
+}
+
+func (analyser *Analyser) Completion(id int, uri string, position lsp.Position) lsp.CompletionResponse {
+	response := lsp.CompletionResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.CompletionItem{},
+	}
+
+	if document, ok := analyser.documents[uri]; ok {
+		// After a '.' only the properties of the object make sense.
+		if class, ok := document.receiverAt(position); ok {
+			if class != nil {
+				response.Result = document.memberCompletions(class)
+			}
+			return response
+		}
+
+		for _, symbol := range document.resolver.Scopes().Visible(position) {
+			kind, ok := declarationCompletionKinds[symbol.Kind]
+			if !ok {
+				continue
+			}
+
+			item := lsp.CompletionItem{
+				Label: symbol.Name,
+				Kind:  kind,
+			}
+			if declaration, ok := document.declared(symbol.Token); ok {
+				item.Detail = declaration.Detail
+			}
+			response.Result = append(response.Result, item)
+		}
+	}
+
+	names := []string{}
+	for keyword := range keywords {
+		names = append(names, keyword)
+	}
+	sort.Strings(names)
+
+	for _, keyword := range names {
+		response.Result = append(response.Result, lsp.CompletionItem{
+			Label: keyword,
+			Kind:  lsp.CompletionItemKindKeyword,
+		})
+	}
+
+	return response
+}
+
+// receiverAt returns the class of the object whose property is being typed
+// at position, just after a '.'. The class is nil when it is not known.
+func (document *Document) receiverAt(position lsp.Position) (*Class, bool) {
+	var class *Class
+	found := false
+	WalkStatements(document.Statements, func(node any) bool {
+		if found {
+			return false
+		}
+
+		var name Token
+		switch node := node.(type) {
+		case *Get:
+			name = node.Name
+		case *Set:
+			name = node.Name
+		default:
+			return true
+		}
+
+		if name.StartLine == position.Line && name.StartChar <= position.Character && position.Character <= name.EndChar {
+			class, _ = document.fields.ObjectClass(node.(Expr))
+			found = true
+			return false
+		}
+		return true
+	})
+
+	return class, found
+}
+
+// memberCompletions offers the fields and then the methods of class,
+// including the ones it inherits.
+func (document *Document) memberCompletions(class *Class) []lsp.CompletionItem {
+	items := []lsp.CompletionItem{}
+	seen := map[string]bool{}
+	for _, field := range document.fields.Of(class) {
+		seen[field.Name.Lexeme] = true
+		items = append(items, lsp.CompletionItem{
+			Label:  field.Name.Lexeme,
+			Kind:   lsp.CompletionItemKindField,
+			Detail: fieldDeclaration(field).Detail,
+		})
+	}
+
+	visited := map[*Class]bool{}
+	for ; class != nil && !visited[class]; class = superclassOf(class, document.resolver) {
+		visited[class] = true
+		for _, method := range class.Methods {
+			if seen[method.Name.Lexeme] {
+				continue
+			}
+			seen[method.Name.Lexeme] = true
+
+			item := lsp.CompletionItem{
+				Label: method.Name.Lexeme,
+				Kind:  lsp.CompletionItemKindMethod,
+			}
+			if declaration, ok := document.declared(method.Name); ok {
+				item.Detail = declaration.Detail
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,34 @@
+package analysis
+
+import "github.com/neet-007/lox_lsp_first/internal/lsp"
+
+func (analyser *Analyser) Definition(id int, uri string, position lsp.Position) lsp.DefinitionResponse {
+	response := lsp.DefinitionResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+	}
+
+	document, ok := analyser.documents[uri]
+	if !ok {
+		return response
+	}
+
+	token, ok := document.tokenAt(position)
+	if !ok {
+		return response
+	}
+
+	declaration, ok := document.declarationOf(token)
+	if !ok {
+		return response
+	}
+
+	response.Result = &lsp.Location{
+		URI:   uri,
+		Range: tokenRange(declaration.Token),
+	}
+
+	return response
+}
@@ -0,0 +1,258 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// Document is what the last analysis of an open file left behind for the
+// hover, definition and completion requests that follow it. An Indexed
+// document is a file of the workspace that was read from disk and is not
+// open.
+type Document struct {
+	Uri          string
+	Source       []byte
+	Indexed      bool
+	Tokens       []Token
+	Statements   []Stmt
+	resolver     *Resolver
+	inference    *Inference
+	fields       *Fields
+	spans        map[any]Span
+	fixes        []lsp.CodeAction
+	declarations []declaration
+}
+
+type DeclarationKind int
+
+const (
+	VARIABLE_DECLARATION DeclarationKind = iota
+	PARAMETER_DECLARATION
+	FUNCTION_DECLARATION
+	CLASS_DECLARATION
+	METHOD_DECLARATION
+	FIELD_DECLARATION
+)
+
+type declaration struct {
+	Token  Token
+	Kind   DeclarationKind
+	Detail string
+}
+
+func NewDocument(uri string, source []byte, tokens []Token, statements []Stmt, resolver *Resolver, inference *Inference, fields *Fields, spans map[any]Span, fixes []lsp.CodeAction) *Document {
+	return &Document{
+		Uri:          uri,
+		Source:       source,
+		Tokens:       tokens,
+		Statements:   statements,
+		resolver:     resolver,
+		inference:    inference,
+		fields:       fields,
+		spans:        spans,
+		fixes:        fixes,
+		declarations: collectDeclarations(statements),
+	}
+}
+
+// tokenAt returns the token under position, preferring names over the
+// punctuation that may touch them.
+func (document *Document) tokenAt(position lsp.Position) (Token, bool) {
+	var found *Token
+	for i := range document.Tokens {
+		token := &document.Tokens[i]
+		if token.Type == EOF || token.StartLine != position.Line {
+			continue
+		}
+		if position.Character < token.StartChar || position.Character > token.EndChar {
+			continue
+		}
+
+		if found == nil || token.Type == IDENTIFIER || token.Type == THIS || token.Type == SUPER {
+			found = token
+		}
+	}
+
+	if found == nil {
+		return Token{}, false
+	}
+
+	return *found, true
+}
+
+// declarationOf finds the declaration token names, or the one it refers to
+// when it is used in an expression.
+func (document *Document) declarationOf(token Token) (declaration, bool) {
+	if member, ok := document.memberOf(token); ok {
+		return member, true
+	}
+
+	if reference, ok := document.referenceAt(token); ok {
+		declared, ok := document.resolver.Declaration(reference)
+		if !ok {
+			return declaration{}, false
+		}
+		token = declared
+	}
+
+	return document.declared(token)
+}
+
+// memberOf returns the method or field that token names when it is the
+// property of a Get or Set on an instance of a known class.
+func (document *Document) memberOf(token Token) (declaration, bool) {
+	var class *Class
+	WalkStatements(document.Statements, func(node any) bool {
+		if class != nil {
+			return false
+		}
+		switch node := node.(type) {
+		case *Get:
+			if node.Name == token {
+				class, _ = document.fields.ObjectClass(node)
+			}
+		case *Set:
+			if node.Name == token {
+				class, _ = document.fields.ObjectClass(node)
+			}
+		}
+		return true
+	})
+	if class == nil {
+		return declaration{}, false
+	}
+
+	if _, method := findMethod(class, token.Lexeme, document.resolver); method != nil {
+		return document.declared(method.Name)
+	}
+	if field, ok := document.fields.Lookup(class, token.Lexeme); ok {
+		return fieldDeclaration(field), true
+	}
+
+	return declaration{}, false
+}
+
+func fieldDeclaration(field Field) declaration {
+	return declaration{
+		Token:  field.Name,
+		Kind:   FIELD_DECLARATION,
+		Detail: fmt.Sprintf("field %s.%s", field.Class.Name.Lexeme, field.Name.Lexeme),
+	}
+}
+
+// declared returns the declaration whose name is token.
+func (document *Document) declared(token Token) (declaration, bool) {
+	for _, decl := range document.declarations {
+		if decl.Token == token {
+			return decl, true
+		}
+	}
+
+	return declaration{}, false
+}
+
+// referenceAt returns the expression whose name is token, if token names
+// a variable, this or super in an expression.
+func (document *Document) referenceAt(token Token) (Expr, bool) {
+	var found Expr
+	WalkStatements(document.Statements, func(node any) bool {
+		if found != nil {
+			return false
+		}
+		if expr, ok := node.(Expr); ok {
+			if name, ok := referenceName(expr); ok && name == token {
+				found = expr
+				return false
+			}
+		}
+		return true
+	})
+
+	return found, found != nil
+}
+
+func collectDeclarations(statements []Stmt) []declaration {
+	declarations := []declaration{}
+
+	var collect func(stmt Stmt)
+	collectFunction := func(function *Function, kind DeclarationKind, prefix string) {
+		params := []string{}
+		for _, param := range function.Params {
+			params = append(params, param.Lexeme)
+		}
+		declarations = append(declarations, declaration{
+			Token:  function.Name,
+			Kind:   kind,
+			Detail: fmt.Sprintf("%s%s(%s)", prefix, function.Name.Lexeme, strings.Join(params, ", ")),
+		})
+
+		for _, param := range function.Params {
+			declarations = append(declarations, declaration{
+				Token:  param,
+				Kind:   PARAMETER_DECLARATION,
+				Detail: fmt.Sprintf("parameter %s of %s", param.Lexeme, function.Name.Lexeme),
+			})
+		}
+
+		for _, stmt := range function.Body {
+			collect(stmt)
+		}
+	}
+
+	collect = func(stmt Stmt) {
+		switch stmt := stmt.(type) {
+		case *Var:
+			declarations = append(declarations, declaration{
+				Token:  stmt.Name,
+				Kind:   VARIABLE_DECLARATION,
+				Detail: fmt.Sprintf("var %s", stmt.Name.Lexeme),
+			})
+		case *Function:
+			collectFunction(stmt, FUNCTION_DECLARATION, "fun ")
+		case *Class:
+			detail := fmt.Sprintf("class %s", stmt.Name.Lexeme)
+			if stmt.Superclass != nil {
+				detail += fmt.Sprintf(" < %s", stmt.Superclass.Name.Lexeme)
+			}
+			declarations = append(declarations, declaration{
+				Token:  stmt.Name,
+				Kind:   CLASS_DECLARATION,
+				Detail: detail,
+			})
+
+			for _, method := range stmt.Methods {
+				collectFunction(method, METHOD_DECLARATION, fmt.Sprintf("method %s.", stmt.Name.Lexeme))
+			}
+		case *Block:
+			for _, inner := range stmt.Statements {
+				collect(inner)
+			}
+		case *If:
+			collect(stmt.ThenBranch)
+			collect(stmt.ElseBranch)
+		case *While:
+			collect(stmt.Body)
+		}
+	}
+
+	for _, stmt := range statements {
+		collect(stmt)
+	}
+
+	return declarations
+}
+
+func tokenRange(token Token) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{
+			Line:      token.StartLine,
+			Character: token.StartChar,
+		},
+		End: lsp.Position{
+			Line:      token.StartLine,
+			Character: token.EndChar,
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+const documentSource = `fun add(a, b) {
+  return a + b;
+}
+var total = add(1, 2);
+print total;
+`
+
+func analyseDocument(t *testing.T) *Analyser {
+	t.Helper()
+
+	analyser := NewAnaylser()
+	analyser.Analyse([]byte(documentSource), "file:///document.lox", log.New(io.Discard, "", 0))
+	return analyser
+}
+
+func TestHover(t *testing.T) {
+	analyser := analyseDocument(t)
+
+	tests := []struct {
+		position lsp.Position
+		want     string
+	}{
+		{position: lsp.Position{Line: 3, Character: 13}, want: "add(a, b)"},
+		{position: lsp.Position{Line: 4, Character: 7}, want: "var total"},
+		{position: lsp.Position{Line: 1, Character: 9}, want: "parameter a of add"},
+	}
+	for _, test := range tests {
+		response := analyser.Hover(1, "file:///document.lox", test.position)
+		if response.Result == nil {
+			t.Errorf("%d:%d: no hover", test.position.Line, test.position.Character)
+			continue
+		}
+		if !strings.Contains(response.Result.Contents, test.want) {
+			t.Errorf("%d:%d: got %q, want it to contain %q", test.position.Line, test.position.Character, response.Result.Contents, test.want)
+		}
+	}
+
+	if response := analyser.Hover(1, "file:///document.lox", lsp.Position{Line: 4, Character: 2}); response.Result != nil {
+		t.Errorf("hover on a keyword: %q", response.Result.Contents)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	analyser := analyseDocument(t)
+
+	response := analyser.Definition(1, "file:///document.lox", lsp.Position{Line: 4, Character: 7})
+	if response.Result == nil {
+		t.Fatal("no definition")
+	}
+
+	want := lsp.Range{
+		Start: lsp.Position{Line: 3, Character: 4},
+		End:   lsp.Position{Line: 3, Character: 9},
+	}
+	if response.Result.Range != want {
+		t.Errorf("got %v, want %v", response.Result.Range, want)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	analyser := analyseDocument(t)
+
+	response := analyser.Completion(1, "file:///document.lox", lsp.Position{Line: 5, Character: 0})
+	kinds := map[string]int{}
+	for _, item := range response.Result {
+		kinds[item.Label] = item.Kind
+	}
+
+	want := map[string]int{
+		"add":   lsp.CompletionItemKindFunction,
+		"total": lsp.CompletionItemKindVariable,
+		"while": lsp.CompletionItemKindKeyword,
+	}
+	for label, kind := range want {
+		if kinds[label] != kind {
+			t.Errorf("%s has kind %d, want %d", label, kinds[label], kind)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package analysis
+
+import "github.com/neet-007/lox_lsp_first/internal/lsp"
+
+// DocumentSymbols returns the outline of a document: its declarations,
+// with the fields and methods of each class and the declarations in each
+// function body under them.
+func (analyser *Analyser) DocumentSymbols(id int, uri string) lsp.DocumentSymbolResponse {
+	response := lsp.DocumentSymbolResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.DocumentSymbol{},
+	}
+
+	if document, ok := analyser.documents[uri]; ok {
+		response.Result = document.symbols(document.Statements)
+	}
+
+	return response
+}
+
+func (document *Document) symbols(statements []Stmt) []lsp.DocumentSymbol {
+	symbols := []lsp.DocumentSymbol{}
+
+	var collect func(stmt Stmt)
+	collect = func(stmt Stmt) {
+		switch stmt := stmt.(type) {
+		case *Var:
+			symbols = append(symbols, document.symbol(stmt.Name, lsp.SymbolKindVariable, tokenRange(stmt.Name)))
+		case *Function:
+			symbols = append(symbols, document.functionSymbol(stmt, lsp.SymbolKindFunction))
+		case *Class:
+			class := document.symbol(stmt.Name, lsp.SymbolKindClass, Span{Start: stmt.Name, End: stmt.End}.Range())
+			class.Children = []lsp.DocumentSymbol{}
+			for _, field := range document.fields.Of(stmt) {
+				if field.Class != stmt {
+					continue
+				}
+				symbol := fieldDeclaration(field)
+				class.Children = append(class.Children, lsp.DocumentSymbol{
+					Name:           field.Name.Lexeme,
+					Detail:         symbol.Detail,
+					Kind:           lsp.SymbolKindField,
+					Range:          tokenRange(field.Name),
+					SelectionRange: tokenRange(field.Name),
+				})
+			}
+			for _, method := range stmt.Methods {
+				class.Children = append(class.Children, document.functionSymbol(method, lsp.SymbolKindMethod))
+			}
+			symbols = append(symbols, class)
+		case *Block:
+			for _, inner := range stmt.Statements {
+				collect(inner)
+			}
+		case *If:
+			collect(stmt.ThenBranch)
+			collect(stmt.ElseBranch)
+		case *While:
+			collect(stmt.Body)
+		}
+	}
+
+	for _, stmt := range statements {
+		collect(stmt)
+	}
+
+	return symbols
+}
+
+func (document *Document) functionSymbol(function *Function, kind int) lsp.DocumentSymbol {
+	symbol := document.symbol(function.Name, kind, Span{Start: function.Name, End: function.End}.Range())
+	symbol.Children = document.symbols(function.Body)
+	return symbol
+}
+
+// symbol makes the symbol of the declaration named name, which spans
+// range_.
+func (document *Document) symbol(name Token, kind int, range_ lsp.Range) lsp.DocumentSymbol {
+	symbol := lsp.DocumentSymbol{
+		Name:           name.Lexeme,
+		Kind:           kind,
+		Range:          range_,
+		SelectionRange: tokenRange(name),
+	}
+	if declaration, ok := document.declared(name); ok {
+		symbol.Detail = declaration.Detail
+	}
+
+	return symbol
+}
@@ -27,10 +27,11 @@ func (env *Environment) Get(token Token) (any, error) {
 func (env *Environment) Assige(token Token, val any) error {
 	if _, ok := env.values[token.Lexeme]; ok {
 		env.values[token.Lexeme] = val
+		return nil
 	}
 
 	if env.enclosing != nil {
-		return env.Assige(token, val)
+		return env.enclosing.Assige(token, val)
 	}
 
 	return &RunTimeError{Code: 1, Message: "cannot assigne to undefined value"}
@@ -40,9 +41,15 @@ func (env *Environment) Define(name string, val any) {
 	env.values[name] = val
 }
 
+// ancestor walks dist environments out, stopping at the outermost one:
+// function bodies are never run, so a distance resolved inside one can
+// reach past the environments that exist.
 func (env *Environment) ancestor(dist int) *Environment {
 	newEnv := env
-	for _ = range dist {
+	for range dist {
+		if newEnv.enclosing == nil {
+			break
+		}
 		newEnv = newEnv.enclosing
 	}
 
@@ -0,0 +1,22 @@
+package analysis
+
+import "testing"
+
+func TestEnvironmentAssign(t *testing.T) {
+	name := Token{Type: IDENTIFIER, Lexeme: "a"}
+
+	global := NewEnvironment(nil)
+	global.Define("a", 1.0)
+	local := NewEnvironment(NewEnvironment(global))
+
+	if err := local.Assige(name, 2.0); err != nil {
+		t.Fatalf("assigning an enclosing variable: %v", err)
+	}
+	if got, _ := global.Get(name); got != 2.0 {
+		t.Errorf("a = %v, want 2", got)
+	}
+
+	if err := local.Assige(Token{Type: IDENTIFIER, Lexeme: "b"}, 1.0); err == nil {
+		t.Error("assigning an undefined variable succeeded")
+	}
+}
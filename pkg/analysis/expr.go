@@ -1,18 +1,19 @@
 package analysis
 
 type VisitExpr interface {
-	VisitAssignExpr(expr Assign) any
-	VisitBinaryExpr(expr Binary) any
-	VisitCallExpr(expr Call) any
-	VisitGetExpr(expr Get) any
-	VisitGroupingExpr(expr Grouping) any
-	VisitLiteralExpr(expr Literal) any
-	VisitLogicalExpr(expr Logical) any
-	VisitSetExpr(expr Set) any
-	VisitSuperExpr(expr Super) any
-	VisitThisExpr(expr This) any
-	VisitUnaryExpr(expr Unary) any
-	VisitVariableExpr(expr Variable) any
+	VisitAssignExpr(expr *Assign) any
+	VisitBinaryExpr(expr *Binary) any
+	VisitCallExpr(expr *Call) any
+	VisitGetExpr(expr *Get) any
+	VisitGroupingExpr(expr *Grouping) any
+	VisitLiteralExpr(expr *Literal) any
+	VisitLogicalExpr(expr *Logical) any
+	VisitMissingExpr(expr *Missing) any
+	VisitSetExpr(expr *Set) any
+	VisitSuperExpr(expr *Super) any
+	VisitThisExpr(expr *This) any
+	VisitUnaryExpr(expr *Unary) any
+	VisitVariableExpr(expr *Variable) any
 }
 
 type Expr interface {
@@ -25,11 +26,11 @@ type Assign struct {
 	Value Expr
 }
 
-func NewAssign(name Token, value Expr) Assign {
-	return Assign{name, value}
+func NewAssign(name Token, value Expr) *Assign {
+	return &Assign{name, value}
 }
 
-func (a Assign) Accept(visitor VisitExpr) any {
+func (a *Assign) Accept(visitor VisitExpr) any {
 	return visitor.VisitAssignExpr(a)
 }
 
@@ -40,11 +41,11 @@ type Binary struct {
 	Right    Expr
 }
 
-func NewBinary(left Expr, operator Token, right Expr) Binary {
-	return Binary{left, operator, right}
+func NewBinary(left Expr, operator Token, right Expr) *Binary {
+	return &Binary{left, operator, right}
 }
 
-func (b Binary) Accept(visitor VisitExpr) any {
+func (b *Binary) Accept(visitor VisitExpr) any {
 	return visitor.VisitBinaryExpr(b)
 }
 
@@ -55,11 +56,11 @@ type Call struct {
 	Arguments []Expr
 }
 
-func NewCall(callee Expr, paren Token, arguments []Expr) Call {
-	return Call{callee, paren, arguments}
+func NewCall(callee Expr, paren Token, arguments []Expr) *Call {
+	return &Call{callee, paren, arguments}
 }
 
-func (c Call) Accept(visitor VisitExpr) any {
+func (c *Call) Accept(visitor VisitExpr) any {
 	return visitor.VisitCallExpr(c)
 }
 
@@ -69,11 +70,11 @@ type Get struct {
 	Name   Token
 }
 
-func NewGet(object Expr, name Token) Get {
-	return Get{object, name}
+func NewGet(object Expr, name Token) *Get {
+	return &Get{object, name}
 }
 
-func (g Get) Accept(visitor VisitExpr) any {
+func (g *Get) Accept(visitor VisitExpr) any {
 	return visitor.VisitGetExpr(g)
 }
 
@@ -82,11 +83,11 @@ type Grouping struct {
 	Expression Expr
 }
 
-func NewGrouping(expression Expr) Grouping {
-	return Grouping{expression}
+func NewGrouping(expression Expr) *Grouping {
+	return &Grouping{expression}
 }
 
-func (g Grouping) Accept(visitor VisitExpr) any {
+func (g *Grouping) Accept(visitor VisitExpr) any {
 	return visitor.VisitGroupingExpr(g)
 }
 
@@ -95,11 +96,11 @@ type Literal struct {
 	Value any
 }
 
-func NewLiteral(value any) Literal {
-	return Literal{value}
+func NewLiteral(value any) *Literal {
+	return &Literal{value}
 }
 
-func (l Literal) Accept(visitor VisitExpr) any {
+func (l *Literal) Accept(visitor VisitExpr) any {
 	return visitor.VisitLiteralExpr(l)
 }
 
@@ -110,14 +111,28 @@ type Logical struct {
 	Right    Expr
 }
 
-func NewLogical(left Expr, operator Token, right Expr) Logical {
-	return Logical{left, operator, right}
+func NewLogical(left Expr, operator Token, right Expr) *Logical {
+	return &Logical{left, operator, right}
 }
 
-func (l Logical) Accept(visitor VisitExpr) any {
+func (l *Logical) Accept(visitor VisitExpr) any {
 	return visitor.VisitLogicalExpr(l)
 }
 
+// Missing stands in for an expression the parser expected but did not find.
+// Token is where it should have started.
+type Missing struct {
+	Token Token
+}
+
+func NewMissing(token Token) *Missing {
+	return &Missing{token}
+}
+
+func (m *Missing) Accept(visitor VisitExpr) any {
+	return visitor.VisitMissingExpr(m)
+}
+
 // Set
 type Set struct {
 	Object Expr
@@ -125,11 +140,11 @@ type Set struct {
 	Value  Expr
 }
 
-func NewSet(object Expr, name Token, value Expr) Set {
-	return Set{object, name, value}
+func NewSet(object Expr, name Token, value Expr) *Set {
+	return &Set{object, name, value}
 }
 
-func (s Set) Accept(visitor VisitExpr) any {
+func (s *Set) Accept(visitor VisitExpr) any {
 	return visitor.VisitSetExpr(s)
 }
 
@@ -139,11 +154,11 @@ type Super struct {
 	Method  Token
 }
 
-func NewSuper(keyword Token, method Token) Super {
-	return Super{keyword, method}
+func NewSuper(keyword Token, method Token) *Super {
+	return &Super{keyword, method}
 }
 
-func (s Super) Accept(visitor VisitExpr) any {
+func (s *Super) Accept(visitor VisitExpr) any {
 	return visitor.VisitSuperExpr(s)
 }
 
@@ -152,11 +167,11 @@ type This struct {
 	Keyword Token
 }
 
-func NewThis(keyword Token) This {
-	return This{keyword}
+func NewThis(keyword Token) *This {
+	return &This{keyword}
 }
 
-func (t This) Accept(visitor VisitExpr) any {
+func (t *This) Accept(visitor VisitExpr) any {
 	return visitor.VisitThisExpr(t)
 }
 
@@ -166,11 +181,11 @@ type Unary struct {
 	Right    Expr
 }
 
-func NewUnary(operator Token, right Expr) Unary {
-	return Unary{operator, right}
+func NewUnary(operator Token, right Expr) *Unary {
+	return &Unary{operator, right}
 }
 
-func (u Unary) Accept(visitor VisitExpr) any {
+func (u *Unary) Accept(visitor VisitExpr) any {
 	return visitor.VisitUnaryExpr(u)
 }
 
@@ -179,10 +194,10 @@ type Variable struct {
 	Name Token
 }
 
-func NewVariable(name Token) Variable {
-	return Variable{name}
+func NewVariable(name Token) *Variable {
+	return &Variable{name}
 }
 
-func (v Variable) Accept(visitor VisitExpr) any {
+func (v *Variable) Accept(visitor VisitExpr) any {
 	return visitor.VisitVariableExpr(v)
 }
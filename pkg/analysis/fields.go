@@ -0,0 +1,185 @@
+package analysis
+
+import "fmt"
+
+// Field is a property that is assigned on instances of Class. Name is the
+// first assignment to it in the source.
+type Field struct {
+	Name  Token
+	Class *Class
+}
+
+// Fields finds the fields of each class in the assignments to properties
+// of this in its methods, and of values that are known to be its instances
+// elsewhere. A property assigned on a value whose class is not known may be
+// a field of any class.
+type Fields struct {
+	resolver  *Resolver
+	inference *Inference
+	classes   []*Class
+	own       map[*Class][]Token
+	anywhere  map[string]bool
+	objects   map[Expr]*Class
+	reads     []*Get
+}
+
+func NewFields(resolver *Resolver, inference *Inference) *Fields {
+	return &Fields{
+		resolver:  resolver,
+		inference: inference,
+		classes:   []*Class{},
+		own:       map[*Class][]Token{},
+		anywhere:  map[string]bool{},
+		objects:   map[Expr]*Class{},
+		reads:     []*Get{},
+	}
+}
+
+// Collect goes through a program for the classes it declares and the
+// properties it gets and sets.
+func (fields *Fields) Collect(statements []Stmt) {
+	var class *Class
+	var visit func(node any) bool
+	visit = func(node any) bool {
+		switch node := node.(type) {
+		case *Class:
+			fields.classes = append(fields.classes, node)
+			enclosing := class
+			class = node
+			for _, method := range node.Methods {
+				Walk(method, visit)
+			}
+			class = enclosing
+			return false
+		case *Get:
+			if object, ok := fields.classOf(node.Object, class); ok {
+				fields.objects[node] = object
+				fields.reads = append(fields.reads, node)
+			}
+		case *Set:
+			object, ok := fields.classOf(node.Object, class)
+			if !ok {
+				fields.anywhere[node.Name.Lexeme] = true
+				break
+			}
+			fields.objects[node] = object
+
+			for _, name := range fields.own[object] {
+				if name.Lexeme == node.Name.Lexeme {
+					return true
+				}
+			}
+			fields.own[object] = append(fields.own[object], node.Name)
+		}
+		return true
+	}
+
+	WalkStatements(statements, visit)
+}
+
+// classOf returns the class that object is an instance of, if it is this
+// in a method of class or inferred to be one.
+func (fields *Fields) classOf(object Expr, class *Class) (*Class, bool) {
+	if _, ok := object.(*This); ok {
+		return class, class != nil
+	}
+
+	t, ok := fields.inference.TypeOf(object)
+	if !ok || t.Kinds != INSTANCE_TYPE || t.Class == nil {
+		return nil, false
+	}
+
+	return t.Class, true
+}
+
+// ObjectClass returns the class of the object of a Get or Set expression,
+// when it is known.
+func (fields *Fields) ObjectClass(expr Expr) (*Class, bool) {
+	class, ok := fields.objects[expr]
+	return class, ok
+}
+
+// Of returns the fields of class and then the ones it inherits that it
+// does not assign itself.
+func (fields *Fields) Of(class *Class) []Field {
+	result := []Field{}
+	seen := map[string]bool{}
+	visited := map[*Class]bool{}
+	for ; class != nil && !visited[class]; class = superclassOf(class, fields.resolver) {
+		visited[class] = true
+		for _, name := range fields.own[class] {
+			if !seen[name.Lexeme] {
+				seen[name.Lexeme] = true
+				result = append(result, Field{Name: name, Class: class})
+			}
+		}
+	}
+
+	return result
+}
+
+// Lookup finds the field name of class or of its superclasses.
+func (fields *Fields) Lookup(class *Class, name string) (Field, bool) {
+	for _, field := range fields.Of(class) {
+		if field.Name.Lexeme == name {
+			return field, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// assigned reports whether name may be a field of an instance of class:
+// it is assigned on class, a class it inherits from, a class inheriting
+// from it, or on some value whose class is not known.
+func (fields *Fields) assigned(class *Class, name string) bool {
+	if fields.anywhere[name] {
+		return true
+	}
+	if _, ok := fields.Lookup(class, name); ok {
+		return true
+	}
+
+	visited := map[*Class]bool{class: true}
+	var inheritedBy func(class *Class) bool
+	inheritedBy = func(class *Class) bool {
+		for _, subclass := range fields.classes {
+			if visited[subclass] || superclassOf(subclass, fields.resolver) != class {
+				continue
+			}
+			visited[subclass] = true
+
+			for _, own := range fields.own[subclass] {
+				if own.Lexeme == name {
+					return true
+				}
+			}
+			if inheritedBy(subclass) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return inheritedBy(class)
+}
+
+// checkFields warns about the properties read from instances of a class
+// that are neither its methods nor fields assigned anywhere.
+func (analyser *Analyser) checkFields(fields *Fields) {
+	for _, get := range fields.reads {
+		class := fields.objects[get]
+		if get.Name.Lexeme == "" || !knownChain(class, fields.resolver) {
+			continue
+		}
+		if _, method := findMethod(class, get.Name.Lexeme, fields.resolver); method != nil {
+			continue
+		}
+		if fields.assigned(class, get.Name.Lexeme) {
+			continue
+		}
+
+		analyser.Warning(get.Name, fmt.Sprintf("field '%s' of '%s' is never assigned",
+			get.Name.Lexeme, class.Name.Lexeme))
+	}
+}
@@ -0,0 +1,132 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		fields      string
+		diagnostics string
+	}{
+		{
+			name: "assigned in methods",
+			source: `class Point {
+  init(x) {
+    this.x = x;
+  }
+  move() {
+    this.y = 1;
+    this.x = this.x + this.z;
+  }
+}
+`,
+			fields: `Point: Point.x Point.y
+`,
+			diagnostics: `6:27-28: field 'z' of 'Point' is never assigned
+`,
+		},
+		{
+			name: "inherited",
+			source: `class Base {
+  init() {
+    this.a = 1;
+  }
+}
+class Derived < Base {
+  init() {
+    super.init();
+    this.b = this.a;
+  }
+}
+`,
+			fields: `Base: Base.a
+Derived: Derived.b Base.a
+`,
+		},
+		{
+			name: "assigned on instances and in subclasses",
+			source: `class Base {
+  show() {
+    print this.label;
+    print this.later;
+  }
+}
+class Derived < Base {
+  init() {
+    this.label = "derived";
+  }
+}
+var base = Base();
+base.later = 1;
+print base.missing;
+print base.show;
+`,
+			fields: `Base: Base.later
+Derived: Derived.label Base.later
+`,
+			diagnostics: `13:11-18: field 'missing' of 'Base' is never assigned
+`,
+		},
+		{
+			name: "unknown objects may have any field",
+			source: `class Box {
+  get() {
+    return this.value;
+  }
+}
+fun fill(box) {
+  box.value = 1;
+}
+`,
+			fields: `Box:
+`,
+		},
+		{
+			name: "this in a nested function",
+			source: `class Counter {
+  init() {
+    fun reset() {
+      this.count = 0;
+    }
+    reset();
+  }
+}
+`,
+			fields: `Counter: Counter.count
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			inference := NewInference(resolver, analyser)
+			inference.Infer(statements)
+			analyser.diagnostics = nil
+
+			fields := NewFields(resolver, inference)
+			fields.Collect(statements)
+			analyser.checkFields(fields)
+
+			var builder strings.Builder
+			for _, class := range fields.classes {
+				builder.WriteString(class.Name.Lexeme + ":")
+				for _, field := range fields.Of(class) {
+					fmt.Fprintf(&builder, " %s.%s", field.Class.Name.Lexeme, field.Name.Lexeme)
+				}
+				builder.WriteString("\n")
+			}
+			if got := builder.String(); got != test.fields {
+				t.Errorf("fields:\n%s\nwant:\n%s", got, test.fields)
+			}
+			if got := formatDiagnostics(analyser); got != test.diagnostics {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.diagnostics)
+			}
+		})
+	}
+}
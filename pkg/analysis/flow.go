@@ -0,0 +1,133 @@
+package analysis
+
+import "fmt"
+
+// checkFlow reports the statements no path reaches, the functions that
+// return a value on some paths but fall off their end on others, and the
+// variables read before or assigned without being read.
+func (analyser *Analyser) checkFlow(statements []Stmt, spans map[any]Span, resolver *Resolver) {
+	for _, cfg := range BuildCFGs(statements) {
+		body := statements
+		if cfg.Function != nil {
+			body = cfg.Function.Body
+		}
+
+		analyser.checkUnreachable(cfg, body, spans)
+		if cfg.Function != nil {
+			analyser.checkReturns(cfg)
+		}
+		analyser.checkAssignments(cfg, resolver)
+	}
+}
+
+// checkUnreachable reports each run of unreachable statements once, with
+// the statements it contains.
+func (analyser *Analyser) checkUnreachable(cfg *CFG, statements []Stmt, spans map[any]Span) {
+	reachable := cfg.Reachable()
+	blockOf := map[any]*BasicBlock{}
+	for _, block := range cfg.Blocks {
+		for _, node := range block.Nodes {
+			blockOf[node] = block
+		}
+	}
+
+	isReachable := func(stmt Stmt) bool {
+		for _, node := range entryNodes(stmt) {
+			if block, ok := blockOf[node]; ok {
+				return reachable[block]
+			}
+		}
+		return true
+	}
+
+	var check func(statements []Stmt)
+	check = func(statements []Stmt) {
+		var run *Span
+		flush := func() {
+			if run != nil {
+				analyser.UnnecessarySpan(*run, "unreachable code")
+				run = nil
+			}
+		}
+
+		for _, stmt := range statements {
+			if stmt == nil {
+				continue
+			}
+
+			if !isReachable(stmt) {
+				if span, ok := spans[stmt]; ok {
+					if run == nil {
+						run = &span
+					} else {
+						run.End = span.End
+					}
+				}
+				continue
+			}
+			flush()
+
+			switch stmt := stmt.(type) {
+			case *Block:
+				check(stmt.Statements)
+			case *If:
+				check([]Stmt{stmt.ThenBranch})
+				check([]Stmt{stmt.ElseBranch})
+			case *While:
+				check([]Stmt{stmt.Body})
+			}
+		}
+		flush()
+	}
+
+	check(statements)
+}
+
+// entryNodes returns the graph nodes that a statement may start with, the
+// one that is evaluated first coming first among those that are nodes.
+func entryNodes(stmt Stmt) []any {
+	switch stmt := stmt.(type) {
+	case *Block:
+		for _, inner := range stmt.Statements {
+			if inner != nil {
+				return entryNodes(inner)
+			}
+		}
+		return nil
+	case *If:
+		return conditionNodes(stmt.Condition)
+	case *While:
+		return conditionNodes(stmt.Condition)
+	}
+
+	return []any{stmt}
+}
+
+func conditionNodes(expr Expr) []any {
+	nodes := []any{expr}
+	switch expr := expr.(type) {
+	case *Grouping:
+		nodes = append(nodes, conditionNodes(expr.Expression)...)
+	case *Logical:
+		nodes = append(nodes, conditionNodes(expr.Left)...)
+	}
+
+	return nodes
+}
+
+// checkReturns warns about a function that returns a value on some paths
+// and reaches the end of its body on others, returning nil there.
+func (analyser *Analyser) checkReturns(cfg *CFG) {
+	reachable := cfg.Reachable()
+	returnsValue := false
+	for _, edge := range cfg.Exit.Predecessors {
+		if ret, ok := edge.From.last().(*Return); ok && reachable[edge.From] {
+			returnsValue = returnsValue || ret.Value != nil
+		}
+	}
+
+	if returnsValue && cfg.FallsOff() {
+		analyser.Warning(cfg.Function.End, fmt.Sprintf(
+			"'%s' returns a value on some paths but reaches its end on others, returning nil", cfg.Function.Name.Lexeme))
+	}
+}
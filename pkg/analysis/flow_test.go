@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFlow(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "after return",
+			source: `fun f() {
+  return 1;
+  print 2;
+  {
+    print 3;
+  }
+}
+`,
+			want: `2:2-5:3: unreachable code
+`,
+		},
+		{
+			name: "after a return in both branches",
+			source: `fun f(a) {
+  if (a) return 1; else return 2;
+  print a;
+}
+`,
+			want: `2:2-2:10: unreachable code
+`,
+		},
+		{
+			name: "literal conditions",
+			source: `if (false) {
+  print 1;
+}
+if (nil) print 1; else print 2;
+while (false) print 3;
+for (;;) print 4;
+print 5;
+`,
+			want: `0:11-2:1: unreachable code
+3:9-3:17: unreachable code
+4:14-4:22: unreachable code
+6:0-6:8: unreachable code
+`,
+		},
+		{
+			name: "short circuit does not make code unreachable",
+			source: `fun f(a) {
+  if (a and true) return 1;
+  print a;
+  return 2;
+}
+`,
+		},
+		{
+			name: "missing return",
+			source: `fun f(a) {
+  if (a) return 1;
+}
+fun g(a) {
+  if (a) return 1;
+  return;
+}
+fun h(a) {
+  while (a) return 1;
+  return 2;
+}
+`,
+			want: `2:0-2:1: 'f' returns a value on some paths but reaches its end on others, returning nil
+`,
+		},
+		{
+			name:   "return at the top level is not the end",
+			source: "return 1;\nprint 2;\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			analyser := NewAnaylser()
+			scanner := NewScanner([]byte(test.source), analyser)
+			parser := NewParser(scanner.Scan(), analyser)
+			statements := parser.Parse()
+			resolver := NewResolver(analyser)
+			resolver.Resolve(statements)
+			analyser.diagnostics = nil
+
+			analyser.checkFlow(statements, parser.Spans(), resolver)
+
+			var builder strings.Builder
+			for _, diagnostic := range analyser.diagnostics {
+				fmt.Fprintf(&builder, "%d:%d-%d:%d: %s\n", diagnostic.Range.Start.Line, diagnostic.Range.Start.Character,
+					diagnostic.Range.End.Line, diagnostic.Range.End.Character, diagnostic.Message)
+			}
+			if got := builder.String(); got != test.want {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package analysis
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+func addFixtures(f *testing.F, add func(source []byte)) {
+	fixtures, err := filepath.Glob("testdata/*/*.lox")
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, fixture := range fixtures {
+		source, err := os.ReadFile(fixture)
+		if err != nil {
+			f.Fatal(err)
+		}
+		add(source)
+	}
+}
+
+func FuzzScanner(f *testing.F) {
+	addFixtures(f, func(source []byte) {
+		f.Add(source)
+	})
+	f.Add([]byte("// comment without a newline"))
+	f.Add([]byte("\"unterminated"))
+
+	f.Fuzz(func(t *testing.T, source []byte) {
+		scanner := NewScanner(source, NewAnaylser())
+		tokens := scanner.Scan()
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != EOF {
+			t.Fatalf("token stream does not end with EOF: %v", tokens)
+		}
+	})
+}
+
+func FuzzAnalyse(f *testing.F) {
+	addFixtures(f, func(source []byte) {
+		f.Add(source, 0, 0)
+	})
+	f.Add([]byte("{ var a; a = ; }"), 0, 9)
+	f.Add([]byte("class A < B { init( { this.x"), 0, 20)
+
+	logger := log.New(io.Discard, "", 0)
+	f.Fuzz(func(t *testing.T, source []byte, line int, character int) {
+		analyser := NewAnaylser()
+		uri := "file:///fuzz.lox"
+		analyser.Analyse(source, uri, logger)
+
+		position := lsp.Position{Line: line, Character: character}
+		analyser.Hover(1, uri, position)
+		analyser.Definition(2, uri, position)
+		analyser.Completion(3, uri, position)
+		analyser.CodeActions(4, uri, lsp.Range{Start: position, End: position}, nil)
+		analyser.CodeActions(5, uri, lsp.Range{End: position}, nil)
+		analyser.InlayHints(6, uri, lsp.Range{End: position})
+	})
+}
+
+func TestParserWithoutEOF(t *testing.T) {
+	analyser := NewAnaylser()
+
+	parser := NewParser([]Token{}, analyser)
+	if statements := parser.Parse(); len(statements) != 0 {
+		t.Errorf("parsed %d statements from no tokens", len(statements))
+	}
+
+	parser = NewParser([]Token{{Type: PRINT, Lexeme: "print"}}, analyser)
+	parser.Parse()
+	if len(analyser.diagnostics) != 1 {
+		t.Errorf("got %d diagnostics for a truncated statement, want 1", len(analyser.diagnostics))
+	}
+}
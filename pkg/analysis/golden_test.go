@@ -0,0 +1,135 @@
+package analysis
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with the file next to fixture that has the given
+// extension, rewriting it instead when the tests run with -update.
+func golden(t *testing.T, fixture string, extension string, got string) {
+	t.Helper()
+
+	path := strings.TrimSuffix(fixture, ".lox") + extension
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func fixtures(t *testing.T) []string {
+	t.Helper()
+
+	fixtures, err := filepath.Glob("testdata/*/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+
+	return fixtures
+}
+
+func formatTokens(tokens []Token) string {
+	var builder strings.Builder
+	for _, token := range tokens {
+		fmt.Fprintf(&builder, "%d:%d-%d %s %q", token.StartLine, token.StartChar, token.EndChar,
+			TokenNames[token.Type], token.Lexeme)
+		if token.Literal != nil {
+			fmt.Fprintf(&builder, " %#v", token.Literal)
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+func formatStatements(statements []Stmt) string {
+	printer := NewAstPrinter()
+
+	var builder strings.Builder
+	for _, stmt := range statements {
+		builder.WriteString(printer.print(stmt))
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+func formatDiagnostics(analyser *Analyser) string {
+	var builder strings.Builder
+	for _, diagnostic := range analyser.diagnostics {
+		fmt.Fprintf(&builder, "%d:%d-%d: %s\n", diagnostic.Range.Start.Line,
+			diagnostic.Range.Start.Character, diagnostic.Range.End.Character, diagnostic.Message)
+	}
+
+	return builder.String()
+}
+
+func TestScannerGolden(t *testing.T) {
+	for _, fixture := range fixtures(t) {
+		t.Run(fixture, func(t *testing.T) {
+			source, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scanner := NewScanner(source, NewAnaylser())
+			golden(t, fixture, ".tokens", formatTokens(scanner.Scan()))
+		})
+	}
+}
+
+func TestParserGolden(t *testing.T) {
+	for _, fixture := range fixtures(t) {
+		t.Run(fixture, func(t *testing.T) {
+			source, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			analyser := NewAnaylser()
+			scanner := NewScanner(source, analyser)
+			parser := NewParser(scanner.Scan(), analyser)
+			golden(t, fixture, ".ast", formatStatements(parser.Parse()))
+		})
+	}
+}
+
+// TestResolverGolden records every diagnostic the scanner, parser and
+// resolver report for a fixture, in the order they were reported.
+func TestResolverGolden(t *testing.T) {
+	for _, fixture := range fixtures(t) {
+		t.Run(fixture, func(t *testing.T) {
+			source, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			analyser := NewAnaylser()
+			scanner := NewScanner(source, analyser)
+			parser := NewParser(scanner.Scan(), analyser)
+			resolver := NewResolver(analyser)
+			resolver.Resolve(parser.Parse())
+			golden(t, fixture, ".diagnostics", formatDiagnostics(analyser))
+		})
+	}
+}
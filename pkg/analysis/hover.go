@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+func (analyser *Analyser) Hover(id int, uri string, position lsp.Position) lsp.HoverResponse {
+	response := lsp.HoverResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+	}
+
+	document, ok := analyser.documents[uri]
+	if !ok {
+		return response
+	}
+
+	token, ok := document.tokenAt(position)
+	if !ok {
+		return response
+	}
+
+	declaration, ok := document.declarationOf(token)
+	if !ok {
+		return response
+	}
+
+	contents := declaration.Detail
+	if t, ok := document.typeAt(token, declaration); ok && t.Known() {
+		contents += ": " + t.String()
+	}
+	if names := document.fieldNames(declaration); len(names) > 0 {
+		contents += "\nfields: " + strings.Join(names, ", ")
+	}
+
+	response.Result = &lsp.HoverResult{
+		Contents: contents,
+	}
+
+	return response
+}
+
+// typeAt returns the inferred type of the variable or parameter named by
+// token: where it is used, the type it has there.
+func (document *Document) typeAt(token Token, declared declaration) (Type, bool) {
+	if declared.Kind != VARIABLE_DECLARATION && declared.Kind != PARAMETER_DECLARATION {
+		return Type{}, false
+	}
+
+	if reference, ok := document.referenceAt(token); ok {
+		return document.inference.TypeOf(reference)
+	}
+
+	return document.inference.DeclaredType(token)
+}
+
+// fieldNames returns the names of the fields of the class declared, own
+// ones first.
+func (document *Document) fieldNames(declared declaration) []string {
+	if declared.Kind != CLASS_DECLARATION {
+		return nil
+	}
+
+	symbol, ok := document.resolver.DeclaredSymbol(declared.Token)
+	if !ok {
+		return nil
+	}
+	class, ok := symbol.Node.(*Class)
+	if !ok {
+		return nil
+	}
+
+	names := []string{}
+	for _, field := range document.fields.Of(class) {
+		names = append(names, field.Name.Lexeme)
+	}
+	return names
+}
@@ -0,0 +1,539 @@
+package analysis
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// maxLoopPasses bounds how often a loop body is inferred before its types
+// are taken as they are.
+const maxLoopPasses = 8
+
+type inferenceState int
+
+const (
+	NOT_INFERRED inferenceState = iota
+	INFERRING
+	INFERRED
+)
+
+// functionInference is what inferring a function body found out.
+type functionInference struct {
+	state   inferenceState
+	returns Type
+}
+
+// Inference follows the statements of a program in order, keeping the type
+// each variable has at that point. Function bodies are inferred once, where
+// they are declared, with unknown parameters. A variable that some other
+// function assigns to is unknown after every call, and a variable declared
+// outside the function that reads it is only known if it is never assigned.
+type Inference struct {
+	analyser   *Analyser
+	resolver   *Resolver
+	types      map[Expr]Type
+	declared   map[Token]Type
+	functions  map[*Function]*functionInference
+	problems   map[Expr]string
+	escaping   map[*Symbol]bool
+	assigned   map[*Symbol]bool
+	env        map[*Symbol]Type
+	reachable  bool
+	class      *Class
+	returns    Type
+	returnsNil bool
+}
+
+func NewInference(resolver *Resolver, analyser *Analyser) *Inference {
+	return &Inference{
+		analyser:  analyser,
+		resolver:  resolver,
+		types:     map[Expr]Type{},
+		declared:  map[Token]Type{},
+		functions: map[*Function]*functionInference{},
+		problems:  map[Expr]string{},
+		escaping:  map[*Symbol]bool{},
+		assigned:  map[*Symbol]bool{},
+		env:       map[*Symbol]Type{},
+		reachable: true,
+	}
+}
+
+// TypeOf returns the type inferred for expr.
+func (inference *Inference) TypeOf(expr Expr) (Type, bool) {
+	t, ok := inference.types[expr]
+	return t, ok
+}
+
+// DeclaredType returns the type of the variable named by token where it is
+// declared.
+func (inference *Inference) DeclaredType(token Token) (Type, bool) {
+	t, ok := inference.declared[token]
+	return t, ok
+}
+
+// ReturnType returns what calling function may return.
+func (inference *Inference) ReturnType(function *Function) Type {
+	inferred, ok := inference.functions[function]
+	if !ok || inferred.state != INFERRED {
+		return AnyType
+	}
+
+	return inferred.returns
+}
+
+// Infer infers the types of a program and reports the operators that are
+// given operands they cannot take.
+func (inference *Inference) Infer(statements []Stmt) {
+	inference.findAssignments(statements)
+
+	for _, stmt := range statements {
+		inference.execute(stmt)
+	}
+
+	// Report in source order; the walk meets a chain of operators at its
+	// last one.
+	operators := []Token{}
+	problems := map[Token]string{}
+	WalkStatements(statements, func(node any) bool {
+		var operator Token
+		switch node := node.(type) {
+		case *Binary:
+			operator = node.Operator
+		case *Unary:
+			operator = node.Operator
+		default:
+			return true
+		}
+		if problem, ok := inference.problems[node.(Expr)]; ok {
+			operators = append(operators, operator)
+			problems[operator] = problem
+		}
+		return true
+	})
+
+	sort.Slice(operators, func(i, j int) bool {
+		return positionBefore(tokenRange(operators[i]).Start, tokenRange(operators[j]).Start)
+	})
+	for _, operator := range operators {
+		inference.analyser.Error(operator, problems[operator])
+	}
+}
+
+// findAssignments marks the assigned variables, and the ones assigned by
+// a function other than the one that declares them.
+func (inference *Inference) findAssignments(statements []Stmt) {
+	global := inference.resolver.Scopes()
+	WalkStatements(statements, func(node any) bool {
+		assign, ok := node.(*Assign)
+		if !ok {
+			return true
+		}
+		symbol, ok := inference.resolver.SymbolOf(assign)
+		if !ok {
+			return true
+		}
+
+		inference.assigned[symbol] = true
+		position := lsp.Position{Line: assign.Name.StartLine, Character: assign.Name.StartChar}
+		if enclosingFunction(global.ScopeAt(position)) != enclosingFunction(symbol.Scope) {
+			inference.escaping[symbol] = true
+		}
+		return true
+	})
+}
+
+// enclosingFunction returns the function or method scope that scope is in,
+// or the global scope.
+func enclosingFunction(scope *Scope) *Scope {
+	for scope.Parent != nil && scope.Kind != FUNCTION_SCOPE && scope.Kind != METHOD_SCOPE {
+		scope = scope.Parent
+	}
+
+	return scope
+}
+
+func (inference *Inference) infer(expr Expr) Type {
+	if expr == nil {
+		return AnyType
+	}
+
+	t := expr.Accept(inference).(Type)
+	inference.types[expr] = t
+	return t
+}
+
+func (inference *Inference) execute(stmt Stmt) {
+	if stmt != nil {
+		stmt.Accept(inference)
+	}
+}
+
+// join merges the variable types of two paths that meet. A path that
+// cannot be reached adds nothing, and a variable only one path knows about
+// is left to what is known about it elsewhere.
+func join(a map[*Symbol]Type, aReachable bool, b map[*Symbol]Type, bReachable bool) map[*Symbol]Type {
+	if !aReachable {
+		return maps.Clone(b)
+	}
+	if !bReachable {
+		return maps.Clone(a)
+	}
+
+	joined := map[*Symbol]Type{}
+	for symbol, t := range a {
+		if other, ok := b[symbol]; ok {
+			joined[symbol] = t.Union(other)
+		}
+	}
+
+	return joined
+}
+
+// declaredType is the type a variable has wherever it is not assigned:
+// what it is declared as.
+func (inference *Inference) declaredType(symbol *Symbol) Type {
+	switch node := symbol.Node.(type) {
+	case *Function:
+		if symbol.Kind == FUNCTION_DECLARATION {
+			return NewFunctionType(node)
+		}
+	case *Class:
+		return NewClassType(node)
+	}
+
+	if t, ok := inference.declared[symbol.Token]; ok {
+		return t
+	}
+
+	return AnyType
+}
+
+func (inference *Inference) read(expr Expr) Type {
+	symbol, ok := inference.resolver.SymbolOf(expr)
+	if !ok {
+		return AnyType
+	}
+
+	if t, ok := inference.env[symbol]; ok {
+		return t
+	}
+	if inference.assigned[symbol] {
+		return AnyType
+	}
+
+	return inference.declaredType(symbol)
+}
+
+// inferFunction infers the body of function once, in its own environment.
+func (inference *Inference) inferFunction(function *Function, class *Class) {
+	if _, ok := inference.functions[function]; ok {
+		return
+	}
+	inferred := &functionInference{state: INFERRING, returns: AnyType}
+	inference.functions[function] = inferred
+
+	env, reachable, enclosingClass := inference.env, inference.reachable, inference.class
+	returns, returnsNil := inference.returns, inference.returnsNil
+	inference.env, inference.reachable, inference.class = map[*Symbol]Type{}, true, class
+	inference.returns, inference.returnsNil = Type{}, false
+
+	for _, param := range function.Params {
+		inference.declared[param] = AnyType
+	}
+	for _, stmt := range function.Body {
+		inference.execute(stmt)
+	}
+
+	inferred.returns = inference.returns
+	if inference.reachable || inference.returnsNil {
+		inferred.returns = inferred.returns.Union(NewType(NIL_TYPE))
+	}
+	if class != nil && function.Name.Lexeme == "init" {
+		inferred.returns = NewInstanceType(class)
+	}
+	inferred.state = INFERRED
+
+	inference.env, inference.reachable, inference.class = env, reachable, enclosingClass
+	inference.returns, inference.returnsNil = returns, returnsNil
+}
+
+// forgetEscaping drops what is known about the variables that a call may
+// assign to.
+func (inference *Inference) forgetEscaping() {
+	for symbol := range inference.env {
+		if inference.escaping[symbol] {
+			delete(inference.env, symbol)
+		}
+	}
+}
+
+func (inference *Inference) VisitAssignExpr(expr *Assign) any {
+	t := inference.infer(expr.Value)
+	if symbol, ok := inference.resolver.SymbolOf(expr); ok {
+		inference.env[symbol] = t
+	}
+
+	return t
+}
+
+func (inference *Inference) VisitBinaryExpr(expr *Binary) any {
+	left := inference.infer(expr.Left)
+	right := inference.infer(expr.Right)
+	delete(inference.problems, expr)
+
+	// The parser has already reported an operand it could not read.
+	if isMissing(expr.Left) || isMissing(expr.Right) {
+		return AnyType
+	}
+
+	switch expr.Operator.Type {
+	case MINUS, STAR, SLASH, LESS, LESS_EQUAL, GREATER, GREATER_EQUAL:
+		if (left.Known() && !left.May(NUMBER_TYPE)) || (right.Known() && !right.May(NUMBER_TYPE)) {
+			inference.problems[expr] = fmt.Sprintf("operands of '%s' must be numbers, got %s and %s",
+				expr.Operator.Lexeme, left, right)
+		}
+
+		if expr.Operator.Type == MINUS || expr.Operator.Type == STAR || expr.Operator.Type == SLASH {
+			return NewType(NUMBER_TYPE)
+		}
+		return NewType(BOOL_TYPE)
+	case PLUS:
+		kinds := TypeKind(0)
+		if left.May(NUMBER_TYPE) && right.May(NUMBER_TYPE) {
+			kinds |= NUMBER_TYPE
+		}
+		if left.May(STRING_TYPE) && right.May(STRING_TYPE) {
+			kinds |= STRING_TYPE
+		}
+
+		if kinds == 0 && (left.Known() || right.Known()) {
+			inference.problems[expr] = fmt.Sprintf("operands of '+' must be two numbers or two strings, got %s and %s",
+				left, right)
+			return AnyType
+		}
+		return NewType(kinds)
+	case EQUAL_EQUAL, BANG_EQUAL:
+		return NewType(BOOL_TYPE)
+	}
+
+	return AnyType
+}
+
+func (inference *Inference) VisitCallExpr(expr *Call) any {
+	callee := inference.infer(expr.Callee)
+	for _, arg := range expr.Arguments {
+		inference.infer(arg)
+	}
+	inference.forgetEscaping()
+
+	switch {
+	case callee.Kinds == FUNCTION_TYPE && callee.Function != nil:
+		return inference.ReturnType(callee.Function)
+	case callee.Kinds == CLASS_TYPE && callee.Class != nil:
+		return NewInstanceType(callee.Class)
+	}
+
+	return AnyType
+}
+
+func (inference *Inference) VisitGetExpr(expr *Get) any {
+	object := inference.infer(expr.Object)
+	if object.Kinds != INSTANCE_TYPE || object.Class == nil {
+		return AnyType
+	}
+
+	if _, method := findMethod(object.Class, expr.Name.Lexeme, inference.resolver); method != nil {
+		return NewFunctionType(method)
+	}
+
+	return AnyType
+}
+
+func (inference *Inference) VisitGroupingExpr(expr *Grouping) any {
+	return inference.infer(expr.Expression)
+}
+
+func (inference *Inference) VisitLiteralExpr(expr *Literal) any {
+	switch expr.Value.(type) {
+	case float64:
+		return NewType(NUMBER_TYPE)
+	case string:
+		return NewType(STRING_TYPE)
+	case bool:
+		return NewType(BOOL_TYPE)
+	case nil:
+		return NewType(NIL_TYPE)
+	}
+
+	return AnyType
+}
+
+func (inference *Inference) VisitLogicalExpr(expr *Logical) any {
+	left := inference.infer(expr.Left)
+
+	// The right operand is only evaluated on some paths.
+	skipped := maps.Clone(inference.env)
+	right := inference.infer(expr.Right)
+	inference.env = join(skipped, true, inference.env, true)
+
+	return left.Union(right)
+}
+
+func (inference *Inference) VisitMissingExpr(expr *Missing) any {
+	return AnyType
+}
+
+func (inference *Inference) VisitSetExpr(expr *Set) any {
+	inference.infer(expr.Object)
+	return inference.infer(expr.Value)
+}
+
+func (inference *Inference) VisitSuperExpr(expr *Super) any {
+	if inference.class == nil {
+		return AnyType
+	}
+
+	superclass := superclassOf(inference.class, inference.resolver)
+	if _, method := findMethod(superclass, expr.Method.Lexeme, inference.resolver); method != nil {
+		return NewFunctionType(method)
+	}
+
+	return AnyType
+}
+
+func (inference *Inference) VisitThisExpr(expr *This) any {
+	if inference.class == nil {
+		return AnyType
+	}
+
+	return NewInstanceType(inference.class)
+}
+
+func (inference *Inference) VisitUnaryExpr(expr *Unary) any {
+	right := inference.infer(expr.Right)
+	delete(inference.problems, expr)
+
+	if expr.Operator.Type == BANG {
+		return NewType(BOOL_TYPE)
+	}
+
+	if right.Known() && !right.May(NUMBER_TYPE) && !isMissing(expr.Right) {
+		inference.problems[expr] = fmt.Sprintf("operand of '-' must be a number, got %s", right)
+	}
+	return NewType(NUMBER_TYPE)
+}
+
+func (inference *Inference) VisitVariableExpr(expr *Variable) any {
+	return inference.read(expr)
+}
+
+func (inference *Inference) VisitBlockStmt(stmt *Block) any {
+	for _, inner := range stmt.Statements {
+		inference.execute(inner)
+	}
+	return nil
+}
+
+func (inference *Inference) VisitClassStmt(stmt *Class) any {
+	if stmt.Superclass != nil {
+		inference.infer(stmt.Superclass)
+	}
+
+	class := NewClassType(stmt)
+	inference.declared[stmt.Name] = class
+	if symbol, ok := inference.resolver.DeclaredSymbol(stmt.Name); ok {
+		inference.env[symbol] = class
+	}
+
+	for _, method := range stmt.Methods {
+		inference.inferFunction(method, stmt)
+	}
+	return nil
+}
+
+func (inference *Inference) VisitExpressionStmt(stmt *Expression) any {
+	inference.infer(stmt.Expression)
+	return nil
+}
+
+func (inference *Inference) VisitFunctionStmt(stmt *Function) any {
+	function := NewFunctionType(stmt)
+	inference.declared[stmt.Name] = function
+	if symbol, ok := inference.resolver.DeclaredSymbol(stmt.Name); ok {
+		inference.env[symbol] = function
+	}
+
+	inference.inferFunction(stmt, nil)
+	return nil
+}
+
+func (inference *Inference) VisitIfStmt(stmt *If) any {
+	inference.infer(stmt.Condition)
+
+	env, reachable := maps.Clone(inference.env), inference.reachable
+	inference.execute(stmt.ThenBranch)
+	thenEnv, thenReachable := inference.env, inference.reachable
+
+	inference.env, inference.reachable = env, reachable
+	inference.execute(stmt.ElseBranch)
+
+	inference.env = join(thenEnv, thenReachable, inference.env, inference.reachable)
+	inference.reachable = thenReachable || inference.reachable
+	return nil
+}
+
+func (inference *Inference) VisitPrintStmt(stmt *Print) any {
+	inference.infer(stmt.Expression)
+	return nil
+}
+
+func (inference *Inference) VisitReturnStmt(stmt *Return) any {
+	if stmt.Value == nil {
+		inference.returnsNil = true
+	} else {
+		inference.returns = inference.returns.Union(inference.infer(stmt.Value))
+	}
+
+	inference.reachable = false
+	return nil
+}
+
+func (inference *Inference) VisitVarStmt(stmt *Var) any {
+	t := NewType(NIL_TYPE)
+	if stmt.Initializer != nil {
+		t = inference.infer(stmt.Initializer)
+	}
+
+	inference.declared[stmt.Name] = t
+	if symbol, ok := inference.resolver.DeclaredSymbol(stmt.Name); ok {
+		inference.env[symbol] = t
+	}
+	return nil
+}
+
+// VisitWhileStmt infers the loop until the types at its start stop
+// changing, then leaves with the types it has when the condition fails.
+func (inference *Inference) VisitWhileStmt(stmt *While) any {
+	entry, reachable := inference.env, inference.reachable
+
+	for range maxLoopPasses {
+		inference.env, inference.reachable = maps.Clone(entry), reachable
+		inference.infer(stmt.Condition)
+		exit := maps.Clone(inference.env)
+
+		inference.execute(stmt.Body)
+		next := join(entry, reachable, inference.env, inference.reachable)
+
+		inference.env, inference.reachable = exit, reachable
+		if maps.Equal(next, entry) {
+			break
+		}
+		entry = next
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatTypes lists the type inferred for every variable reference of a
+// program in source order.
+func formatTypes(statements []Stmt, inference *Inference) string {
+	var builder strings.Builder
+	WalkStatements(statements, func(node any) bool {
+		variable, ok := node.(*Variable)
+		if !ok {
+			return true
+		}
+
+		t, _ := inference.TypeOf(variable)
+		fmt.Fprintf(&builder, "%s %d:%d %s\n", variable.Name.Lexeme, variable.Name.StartLine, variable.Name.StartChar, t)
+		return true
+	})
+
+	return builder.String()
+}
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		types       string
+		diagnostics string
+	}{
+		{
+			name: "assignments follow the flow",
+			source: `var a = 1;
+print a;
+a = "one";
+print a;
+if (a == "one") a = nil; else a = true;
+print a;
+`,
+			types: `a 1:6 number
+a 3:6 string
+a 4:4 string
+a 5:6 bool | nil
+`,
+		},
+		{
+			name: "loops widen",
+			source: `var i = 0;
+var s = nil;
+while (i < 10) {
+  s = "x";
+  i = i + 1;
+}
+print s;
+`,
+			types: `i 2:7 number
+i 4:6 number
+s 6:6 string | nil
+`,
+		},
+		{
+			name: "functions and classes",
+			source: `fun twice(n) {
+  return n * 2;
+}
+fun maybe(n) {
+  if (n) return "yes";
+}
+class Point {
+  init(x) {
+    this.x = x;
+  }
+  norm() {
+    return this;
+  }
+}
+var t = twice(1);
+var m = maybe(1);
+var p = Point(1);
+var q = p.norm();
+print t + m + p + q;
+`,
+			types: `n 1:9 any
+n 4:6 any
+x 8:13 any
+twice 14:8 fun(n)
+maybe 15:8 fun(n)
+Point 16:8 class Point
+p 17:8 Point
+t 18:6 number
+m 18:10 string | nil
+p 18:14 Point
+q 18:18 Point
+`,
+			diagnostics: `18:8-9: operands of '+' must be two numbers or two strings, got number and string | nil
+18:12-13: operands of '+' must be two numbers or two strings, got any and Point
+18:16-17: operands of '+' must be two numbers or two strings, got any and Point
+`,
+		},
+		{
+			name: "variables assigned by a call are forgotten",
+			source: `var count = 0;
+fun reset() {
+  count = "none";
+}
+print count;
+reset();
+print count;
+fun read() {
+  print count;
+}
+`,
+			types: `count 4:6 number
+reset 5:0 fun()
+count 6:6 any
+count 8:8 any
+`,
+		},
+		{
+			name: "short circuit",
+			source: `var a = nil;
+var b = a or "default";
+true and (a = 1);
+print a;
+print -b;
+`,
+			types: `a 1:8 nil
+a 3:6 number | nil
+b 4:7 string | nil
+`,
+			diagnostics: `4:6-7: operand of '-' must be a number, got string | nil
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			inference := NewInference(resolver, analyser)
+			inference.Infer(statements)
+
+			if got := formatTypes(statements, inference); got != test.types {
+				t.Errorf("types:\n%s\nwant:\n%s", got, test.types)
+			}
+			if got := formatDiagnostics(analyser); got != test.diagnostics {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// checkInheritance reports superclasses that are not declared, are declared
+// after the class that inherits from them, or are not classes, classes that
+// inherit from themselves through others, and super calls to methods that
+// no superclass has.
+func (analyser *Analyser) checkInheritance(statements []Stmt, resolver *Resolver, inference *Inference) {
+	var class *Class
+	var visit func(node any) bool
+	visit = func(node any) bool {
+		switch node := node.(type) {
+		case *Class:
+			if node.Superclass != nil {
+				analyser.checkSuperclass(node, resolver, inference)
+			}
+
+			enclosing := class
+			class = node
+			for _, method := range node.Methods {
+				Walk(method, visit)
+			}
+			class = enclosing
+			return false
+		case *Super:
+			if class != nil && class.Superclass != nil {
+				analyser.checkSuperMethod(node, class, resolver)
+			}
+		}
+		return true
+	}
+
+	WalkStatements(statements, visit)
+}
+
+func (analyser *Analyser) checkSuperclass(class *Class, resolver *Resolver, inference *Inference) {
+	name := class.Superclass.Name
+	position := lsp.Position{Line: name.StartLine, Character: name.StartChar}
+
+	symbol, ok := resolver.SymbolOf(class.Superclass)
+	if !ok {
+		// A local is only bound once it is declared.
+		if later, ok := resolver.Scopes().ScopeAt(position).Lookup(name.Lexeme); ok {
+			analyser.declaredAfter(class, later)
+			return
+		}
+
+		message := fmt.Sprintf("superclass '%s' is not declared", name.Lexeme)
+		suggestion, ok := suggestName(name.Lexeme, position, resolver.Scopes())
+		if ok {
+			message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		diagnostic := analyser.Error(name, message)
+		if ok {
+			analyser.renameFix(diagnostic, name, suggestion)
+		}
+		return
+	}
+
+	if !declaredBefore(symbol, position, resolver.Scopes()) {
+		analyser.declaredAfter(class, symbol)
+	}
+
+	switch symbol.Kind {
+	case CLASS_DECLARATION:
+		// A class inheriting from itself directly is reported by the
+		// resolver.
+		if cycle := inheritanceCycle(class, resolver); len(cycle) > 2 {
+			analyser.Error(name, fmt.Sprintf("inheritance cycle: %s", strings.Join(cycle, " < ")))
+		}
+	case FUNCTION_DECLARATION:
+		analyser.Error(name, fmt.Sprintf("superclass '%s' is a function, not a class", name.Lexeme))
+	case VARIABLE_DECLARATION, PARAMETER_DECLARATION:
+		t, ok := inference.TypeOf(class.Superclass)
+		switch {
+		case ok && t.Kinds == CLASS_TYPE:
+		case ok && !t.May(CLASS_TYPE):
+			analyser.Error(name, fmt.Sprintf("superclass '%s' is a variable of type %s, not a class", name.Lexeme, t))
+		default:
+			analyser.Warning(name, fmt.Sprintf("superclass '%s' is a variable, which may not hold a class", name.Lexeme))
+		}
+	}
+}
+
+func (analyser *Analyser) declaredAfter(class *Class, superclass *Symbol) {
+	analyser.related(analyser.Error(class.Superclass.Name, fmt.Sprintf("superclass '%s' is declared after '%s'",
+		superclass.Name, class.Name.Lexeme)), superclass.Token)
+}
+
+// inheritanceCycle returns the names of the classes from class back to
+// itself when following its superclasses leads there.
+func inheritanceCycle(class *Class, resolver *Resolver) []string {
+	names := []string{class.Name.Lexeme}
+	seen := map[*Class]bool{}
+	for superclass := superclassOf(class, resolver); superclass != nil && !seen[superclass]; superclass = superclassOf(superclass, resolver) {
+		seen[superclass] = true
+		names = append(names, superclass.Name.Lexeme)
+		if superclass == class {
+			return names
+		}
+	}
+
+	return nil
+}
+
+// checkSuperMethod reports super.method when every superclass of class is
+// known and none of them has the method.
+func (analyser *Analyser) checkSuperMethod(super *Super, class *Class, resolver *Resolver) {
+	superclass := superclassOf(class, resolver)
+	if super.Method.Lexeme == "" || superclass == nil || !knownChain(superclass, resolver) {
+		return
+	}
+
+	if _, method := findMethod(superclass, super.Method.Lexeme, resolver); method == nil {
+		analyser.Error(super.Method, fmt.Sprintf("'%s' and its superclasses have no method '%s'",
+			superclass.Name.Lexeme, super.Method.Lexeme))
+	}
+}
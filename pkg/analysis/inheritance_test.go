@@ -0,0 +1,103 @@
+package analysis
+
+import "testing"
+
+func TestInheritance(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "cycle through three classes",
+			source: `class A < C {}
+class B < A {}
+class C < B {}
+`,
+			want: `0:10-11: superclass 'C' is declared after 'A'
+0:10-11: inheritance cycle: A < C < B < A
+1:10-11: inheritance cycle: B < A < C < B
+2:10-11: inheritance cycle: C < B < A < C
+`,
+		},
+		{
+			name: "local declared later",
+			source: `fun f() {
+  class A < B {}
+  class B {}
+  return A;
+}
+`,
+			want: `1:12-13: superclass 'B' is declared after 'A'
+`,
+		},
+		{
+			name: "a global declared later is fine in a function",
+			source: `fun f() {
+  class Local < Base {}
+  return Local;
+}
+class Base {}
+`,
+		},
+		{
+			name: "not a class",
+			source: `var name = "x";
+class A < name {}
+fun g() {}
+class B < g {}
+var C = A;
+class D < C {}
+`,
+			want: `1:10-14: superclass 'name' is a variable of type string, not a class
+3:10-11: superclass 'g' is a function, not a class
+`,
+		},
+		{
+			name: "super methods",
+			source: `class A {
+  greet() {}
+}
+class B < A {}
+class C < B {
+  greet() {
+    super.greet();
+    super.wave();
+  }
+}
+`,
+			want: `7:10-14: 'B' and its superclasses have no method 'wave'
+`,
+		},
+		{
+			name: "unknown superclass may have any method",
+			source: `fun f(base) {
+  class A < base {}
+  class B < A {
+    run() {
+      super.run();
+    }
+  }
+  return B;
+}
+`,
+			want: `1:12-16: superclass 'base' is a variable, which may not hold a class
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			inference := NewInference(resolver, analyser)
+			inference.Infer(statements)
+			analyser.diagnostics = nil
+
+			analyser.checkInheritance(statements, resolver, inference)
+
+			if got := formatDiagnostics(analyser); got != test.want {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// InlayHints returns the hints the settings ask for within range_: the
+// names of the parameters that the arguments of calls are for, the types
+// inferred for variables, and the nil that functions return implicitly.
+func (analyser *Analyser) InlayHints(id int, uri string, range_ lsp.Range) lsp.InlayHintResponse {
+	response := lsp.InlayHintResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.InlayHint{},
+	}
+
+	document, ok := analyser.documents[uri]
+	if !ok {
+		return response
+	}
+
+	settings := analyser.settings.InlayHints
+	hints := []lsp.InlayHint{}
+	if settings.ParameterNames {
+		hints = append(hints, document.parameterHints()...)
+	}
+	if settings.VariableTypes {
+		hints = append(hints, document.typeHints()...)
+	}
+	if settings.ImplicitReturns {
+		hints = append(hints, document.returnHints()...)
+	}
+
+	for _, hint := range hints {
+		if !positionBefore(hint.Position, range_.Start) && !positionBefore(range_.End, hint.Position) {
+			response.Result = append(response.Result, hint)
+		}
+	}
+	sort.SliceStable(response.Result, func(i, j int) bool {
+		return positionBefore(response.Result[i].Position, response.Result[j].Position)
+	})
+
+	return response
+}
+
+// parameterHints names the parameter before each argument of a call to a
+// known function, method or class. An argument that is a variable named
+// like its parameter needs no hint.
+func (document *Document) parameterHints() []lsp.InlayHint {
+	hints := []lsp.InlayHint{}
+	for _, site := range document.callSites(nil) {
+		function, ok := site.callee.(*Function)
+		if class, isClass := site.callee.(*Class); isClass {
+			_, function = findMethod(class, "init", document.resolver)
+			ok = function != nil
+		}
+		if !ok {
+			continue
+		}
+
+		for i, argument := range site.call.Arguments {
+			if i >= len(function.Params) {
+				break
+			}
+			param := function.Params[i]
+			if variable, ok := argument.(*Variable); ok && variable.Name.Lexeme == param.Lexeme {
+				continue
+			}
+			span, ok := document.spans[argument]
+			if !ok {
+				continue
+			}
+
+			hints = append(hints, lsp.InlayHint{
+				Position:     lsp.Position{Line: span.Start.StartLine, Character: span.Start.StartChar},
+				Label:        param.Lexeme + ":",
+				Kind:         lsp.InlayHintKindParameter,
+				PaddingRight: true,
+			})
+		}
+	}
+
+	return hints
+}
+
+// typeHints shows the type inferred for each variable after its name,
+// unless its initializer is a literal that already says it.
+func (document *Document) typeHints() []lsp.InlayHint {
+	hints := []lsp.InlayHint{}
+	WalkStatements(document.Statements, func(node any) bool {
+		stmt, ok := node.(*Var)
+		if !ok || stmt.Initializer == nil {
+			return true
+		}
+		if _, ok := stmt.Initializer.(*Literal); ok {
+			return true
+		}
+
+		if t, ok := document.inference.DeclaredType(stmt.Name); ok && t.Known() {
+			hints = append(hints, lsp.InlayHint{
+				Position: lsp.Position{Line: stmt.Name.StartLine, Character: stmt.Name.EndChar},
+				Label:    ": " + t.String(),
+				Kind:     lsp.InlayHintKindType,
+			})
+		}
+		return true
+	})
+
+	return hints
+}
+
+// returnHints shows the nil returned by return statements without a value
+// and by functions whose end can be reached. Initializers return the
+// instance instead, so they are left out.
+func (document *Document) returnHints() []lsp.InlayHint {
+	hints := []lsp.InlayHint{}
+	fallsOff := map[*Function]bool{}
+	for _, cfg := range BuildCFGs(document.Statements) {
+		if cfg.Function != nil {
+			fallsOff[cfg.Function] = cfg.FallsOff()
+		}
+	}
+
+	var initializer bool
+	var visit func(node any) bool
+	visit = func(node any) bool {
+		switch node := node.(type) {
+		case *Class:
+			for _, method := range node.Methods {
+				enclosing := initializer
+				initializer = method.Name.Lexeme == "init"
+				WalkStatements(method.Body, visit)
+				if !initializer && fallsOff[method] {
+					hints = append(hints, implicitReturnHint(method))
+				}
+				initializer = enclosing
+			}
+			return false
+		case *Function:
+			enclosing := initializer
+			initializer = false
+			WalkStatements(node.Body, visit)
+			if fallsOff[node] {
+				hints = append(hints, implicitReturnHint(node))
+			}
+			initializer = enclosing
+			return false
+		case *Return:
+			if node.Value == nil && !initializer {
+				hints = append(hints, lsp.InlayHint{
+					Position:    lsp.Position{Line: node.Keyword.StartLine, Character: node.Keyword.EndChar},
+					Label:       "nil",
+					Kind:        lsp.InlayHintKindType,
+					PaddingLeft: true,
+				})
+			}
+		}
+		return true
+	}
+	WalkStatements(document.Statements, visit)
+
+	return hints
+}
+
+// implicitReturnHint is shown before the closing brace of function.
+func implicitReturnHint(function *Function) lsp.InlayHint {
+	return lsp.InlayHint{
+		Position:     lsp.Position{Line: function.End.StartLine, Character: function.End.StartChar},
+		Label:        "return nil",
+		Kind:         lsp.InlayHintKindType,
+		PaddingRight: true,
+	}
+}
@@ -0,0 +1,34 @@
+package analysis
+
+import (
+	"io"
+	"log"
+	"testing"
+)
+
+func TestImplicitReturnHints(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{source: "fun f() { print 1; }", want: true},
+		{source: "fun f(x) { if (x) return 1; }", want: true},
+		{source: "fun f(x) { if (x) return 1; else return 2; }", want: false},
+		{source: "fun f() { while (true) { return 1; } }", want: false},
+		{source: "fun f(x) { for (;;) { if (x) return 1; } }", want: false},
+		{source: "fun f(x) { while (x) { return 1; } }", want: true},
+	}
+
+	for _, test := range tests {
+		analyser := NewAnaylser()
+		analyser.Analyse([]byte(test.source), "file:///hints.lox", log.New(io.Discard, "", 0))
+
+		got := false
+		for _, hint := range analyser.documents["file:///hints.lox"].returnHints() {
+			got = got || hint.Label == "return nil"
+		}
+		if got != test.want {
+			t.Errorf("%s: return nil hint = %v, want %v", test.source, got, test.want)
+		}
+	}
+}
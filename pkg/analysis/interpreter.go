@@ -38,8 +38,8 @@ func (interpreter *Interpreter) Interpert(statements []Stmt) error {
 	return nil
 }
 
-func (interpreter *Interpreter) lookupVariable(name Token, variable Expr) (any, error) {
-	if val, ok := interpreter.locals[variable]; ok {
+func (interpreter *Interpreter) lookupVariable(name Token, expr Expr) (any, error) {
+	if val, ok := interpreter.locals[expr]; ok {
 		ret, err := interpreter.environment.GetAT(name, val)
 		if err != nil {
 			return nil, err
@@ -56,152 +56,130 @@ func (interpreter *Interpreter) lookupVariable(name Token, variable Expr) (any,
 	return ret, nil
 }
 
-func (interpreter *Interpreter) checkNumberOperand(operator Token, operand any) (any, error) {
-	if _, ok := operand.(float64); ok {
-		return nil, nil
-	}
-
-	return nil, &RunTimeError{Code: 1, Message: "operand must be number"}
-}
-
-func (interpreter *Interpreter) checkNumberOperands(operator Token, left, right any) (any, error) {
-	if _, okLeft := left.(float64); okLeft {
-		if _, okRight := right.(float64); okRight {
-			return nil, nil
-		}
+func (interpreter *Interpreter) evaluate(expr Expr) any {
+	if expr == nil {
+		return nil
 	}
 
-	return nil, &RunTimeError{Code: 1, Message: "operands must be numbers"}
-}
-
-func (interpreter *Interpreter) evaluate(expr Expr) any {
 	return expr.Accept(interpreter)
 }
 
 func (interpreter *Interpreter) execute(stmt Stmt) {
+	if stmt == nil {
+		return
+	}
+
 	stmt.Accept(interpreter)
 }
 
-func (interpreter *Interpreter) VisitAssignExpr(expr Assign) any {
+func (interpreter *Interpreter) VisitAssignExpr(expr *Assign) any {
 	value := interpreter.evaluate(expr.Value)
+	// Undefined names are reported by checkUndefined, which also knows
+	// about the ones this evaluation never reaches.
 	if dist, ok := interpreter.locals[expr]; ok {
-		err := interpreter.environment.AssignAT(expr.Name, dist, value)
-		if err != nil {
-			interpreter.analyser.Error(expr.Name, err.Error())
-		}
-
+		interpreter.environment.AssignAT(expr.Name, dist, value)
 		return value
 	}
-	err := interpreter.globals.Assige(expr.Name, value)
-	if err != nil {
-		interpreter.analyser.Error(expr.Name, err.Error())
-	}
+	interpreter.globals.Assige(expr.Name, value)
 
 	return value
 }
 
-func (interpreter *Interpreter) VisitBinaryExpr(expr Binary) any {
+func (interpreter *Interpreter) VisitBinaryExpr(expr *Binary) any {
 	left := interpreter.evaluate(expr.Left)
 	rigth := interpreter.evaluate(expr.Right)
 
+	// Operand types are checked by the type inference, which also sees the
+	// code this evaluation never reaches.
 	switch expr.Operator.Type {
-	case LESS:
-	case LESS_EQUAL:
-	case GREATER:
-	case GREATER_EQUAL:
-		interpreter.checkNumberOperands(expr.Operator, left, rigth)
+	case LESS, LESS_EQUAL, GREATER, GREATER_EQUAL, EQUAL_EQUAL, BANG_EQUAL:
 		return false
-	case MINUS:
-	case STAR:
-	case SLASH:
-		interpreter.checkNumberOperands(expr.Operator, left, rigth)
-		return 0
+	case MINUS, STAR, SLASH:
+		return 0.0
 	case PLUS:
-		{
-			if _, ok := left.(string); ok {
-				if _, ok := rigth.(string); ok {
-					return ""
-				}
-
-				interpreter.analyser.Error(expr.Operator, "both operands must be strings")
-				return nil
-			}
-			if _, ok := left.(float64); ok {
-				if _, ok := rigth.(float64); ok {
-					return 0
-				}
-
-				interpreter.analyser.Error(expr.Operator, "both operands must be number")
-				return nil
-			}
-			interpreter.analyser.Error(expr.Operator, "operands must be numbers or strings")
-			return nil
+		_, leftString := left.(string)
+		_, rightString := rigth.(string)
+		if leftString && rightString {
+			return ""
 		}
-	default:
-		interpreter.analyser.Error(expr.Operator, "binary must be +, -, *, /, <, <=, >, >=, ==")
-		return nil
+		return 0.0
 	}
 
 	return nil
 }
 
-func (interpreter *Interpreter) VisitCallExpr(expr Call) any {
+func (interpreter *Interpreter) VisitCallExpr(expr *Call) any {
 	callee := interpreter.evaluate(expr.Callee)
 
-	//!TODO error here
-	function, ok := callee.(LoxFunction)
-	if !ok {
-		interpreter.analyser.Error(expr.Paren, "only functions and classes can be called")
+	args := make([]any, 0, len(expr.Arguments))
+	for _, arg := range expr.Arguments {
+		args = append(args, interpreter.evaluate(arg))
+	}
+
+	// nil is a value this evaluation does not know, not necessarily nil.
+	if callee == nil {
 		return nil
 	}
 
-	if len(expr.Arguments) != function.Arity() {
-		fmt.Println("herekj")
-		interpreter.analyser.Error(expr.Paren, fmt.Sprintf("needs %d arguments, got %d", len(expr.Arguments), function.Arity()))
+	function, ok := callee.(LoxCallable)
+	if !ok {
+		interpreter.analyser.Error(expr.Paren, "only functions and classes can be called")
 		return nil
 	}
 
-	fmt.Println("herekj")
-	for _, arg := range expr.Arguments {
-		interpreter.evaluate(arg)
+	// Wrong argument counts are reported by checkArity.
+	if len(args) != function.Arity() {
+		return nil
 	}
 
-	fmt.Println("herekj")
-	return function.Call(expr.Arguments)
+	return function.Call(args...)
 }
 
-func (interpreter *Interpreter) VisitGetExpr(expr Get) any {
+func (interpreter *Interpreter) VisitGetExpr(expr *Get) any {
 	object := interpreter.evaluate(expr.Object)
 
-	objectInstance, ok := object.(LoxInstance)
+	objectInstance, ok := object.(*LoxInstance)
 	if !ok {
-		interpreter.analyser.Error(expr.Name, "only instances have proprerty")
+		if object != nil {
+			interpreter.analyser.Error(expr.Name, "only instances have proprerty")
+		}
 		return nil
 	}
 
 	return objectInstance.get(expr.Name)
 }
 
-func (interpreter *Interpreter) VisitGroupingExpr(expr Grouping) any {
+func (interpreter *Interpreter) VisitGroupingExpr(expr *Grouping) any {
 	return interpreter.evaluate(expr.Expression)
 }
 
-func (interpreter *Interpreter) VisitLiteralExpr(expr Literal) any {
+func (interpreter *Interpreter) VisitLiteralExpr(expr *Literal) any {
 	return expr.Value
 }
 
-func (interpreter *Interpreter) VisitLogicalExpr(expr Logical) any {
+func (interpreter *Interpreter) VisitLogicalExpr(expr *Logical) any {
 	interpreter.evaluate(expr.Left)
 	interpreter.evaluate(expr.Right)
 	return false
 }
 
-func (interpreter *Interpreter) VisitSetExpr(expr Set) any {
+func (interpreter *Interpreter) VisitMissingExpr(expr *Missing) any {
+	return nil
+}
+
+func isMissing(expr Expr) bool {
+	_, ok := expr.(*Missing)
+	return ok
+}
+
+func (interpreter *Interpreter) VisitSetExpr(expr *Set) any {
 	object := interpreter.evaluate(expr.Object)
 
-	objectInstance, ok := object.(LoxInstance)
+	objectInstance, ok := object.(*LoxInstance)
 	if !ok {
-		interpreter.analyser.Error(expr.Name, "only instances have proprerty")
+		if object != nil {
+			interpreter.analyser.Error(expr.Name, "only instances have proprerty")
+		}
 		return nil
 	}
 
@@ -210,50 +188,43 @@ func (interpreter *Interpreter) VisitSetExpr(expr Set) any {
 	return value
 }
 
-func (interpreter *Interpreter) VisitSuperExpr(expr Super) any {
+func (interpreter *Interpreter) VisitSuperExpr(expr *Super) any {
 
 	return nil
 }
 
-func (interpreter *Interpreter) VisitThisExpr(expr This) any {
-	ret, err := interpreter.lookupVariable(expr.Keyword, expr)
-	if err != nil {
-		interpreter.analyser.Error(expr.Keyword, err.Error())
-	}
+func (interpreter *Interpreter) VisitThisExpr(expr *This) any {
+	ret, _ := interpreter.lookupVariable(expr.Keyword, expr)
 	return ret
 }
 
-func (interpreter *Interpreter) VisitUnaryExpr(expr Unary) any {
+func (interpreter *Interpreter) VisitUnaryExpr(expr *Unary) any {
 	return interpreter.evaluate(expr.Right)
 }
 
-func (interpreter *Interpreter) VisitVariableExpr(expr Variable) any {
-	ret, err := interpreter.lookupVariable(expr.Name, expr)
-	if err != nil {
-		interpreter.analyser.Error(expr.Name, err.Error())
-	}
+func (interpreter *Interpreter) VisitVariableExpr(expr *Variable) any {
+	ret, _ := interpreter.lookupVariable(expr.Name, expr)
 	return ret
 }
 
-func (interpreter *Interpreter) VisitBlockStmt(stmt Block) any {
+func (interpreter *Interpreter) VisitBlockStmt(stmt *Block) any {
+	previous := interpreter.environment
+	if !stmt.Sequence {
+		interpreter.environment = NewEnvironment(previous)
+	}
 	for _, stmt := range stmt.Statements {
 		interpreter.execute(stmt)
 	}
+	interpreter.environment = previous
 	return nil
 }
 
-func (interpreter *Interpreter) VisitClassStmt(stmt Class) any {
+func (interpreter *Interpreter) VisitClassStmt(stmt *Class) any {
+	// A superclass that is not a class is reported by checkInheritance; the
+	// class is still defined, without one.
 	var superclass *LoxClass = nil
-	var zeroVariable Variable
-
-	if stmt.Superclass != zeroVariable {
-		superclass := interpreter.evaluate(stmt.Superclass)
-		superclassClass, ok := superclass.(*LoxClass)
-		if !ok {
-			interpreter.analyser.Error(stmt.Name, "can only inherit from classes")
-			return nil
-		}
-		superclass = superclassClass
+	if stmt.Superclass != nil {
+		superclass, _ = interpreter.evaluate(stmt.Superclass).(*LoxClass)
 	}
 
 	interpreter.environment.Define(stmt.Name.Lexeme, nil)
@@ -279,19 +250,19 @@ func (interpreter *Interpreter) VisitClassStmt(stmt Class) any {
 	return nil
 }
 
-func (interpreter *Interpreter) VisitExpressionStmt(stmt Expression) any {
+func (interpreter *Interpreter) VisitExpressionStmt(stmt *Expression) any {
 	interpreter.evaluate(stmt.Expression)
 	return nil
 }
 
-func (interpreter *Interpreter) VisitFunctionStmt(stmt Function) any {
+func (interpreter *Interpreter) VisitFunctionStmt(stmt *Function) any {
 	function := NewLoxFunction(stmt, interpreter.environment, false)
 
-	interpreter.environment.Assige(stmt.Name, function)
+	interpreter.environment.Define(stmt.Name.Lexeme, function)
 	return nil
 }
 
-func (interpreter *Interpreter) VisitIfStmt(stmt If) any {
+func (interpreter *Interpreter) VisitIfStmt(stmt *If) any {
 	interpreter.evaluate(stmt.Condition)
 	interpreter.execute(stmt.ThenBranch)
 	if stmt.ElseBranch != nil {
@@ -300,17 +271,17 @@ func (interpreter *Interpreter) VisitIfStmt(stmt If) any {
 	return nil
 }
 
-func (interpreter *Interpreter) VisitPrintStmt(stmt Print) any {
+func (interpreter *Interpreter) VisitPrintStmt(stmt *Print) any {
 	interpreter.evaluate(stmt.Expression)
 	return nil
 }
 
-func (interpreter *Interpreter) VisitReturnStmt(stmt Return) any {
+func (interpreter *Interpreter) VisitReturnStmt(stmt *Return) any {
 
 	return nil
 }
 
-func (interpreter *Interpreter) VisitVarStmt(stmt Var) any {
+func (interpreter *Interpreter) VisitVarStmt(stmt *Var) any {
 	var value any = nil
 	if stmt.Initializer != nil {
 		value = interpreter.evaluate(stmt.Initializer)
@@ -320,7 +291,7 @@ func (interpreter *Interpreter) VisitVarStmt(stmt Var) any {
 	return nil
 }
 
-func (interpreter *Interpreter) VisitWhileStmt(stmt While) any {
+func (interpreter *Interpreter) VisitWhileStmt(stmt *While) any {
 	interpreter.evaluate(stmt.Condition)
 	interpreter.execute(stmt.Body)
 	return nil
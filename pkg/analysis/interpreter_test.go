@@ -0,0 +1,23 @@
+package analysis
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestInterpreterEquality(t *testing.T) {
+	source := `var a = 1;
+print a == 2;
+print a != "a";
+print nil == nil;
+`
+
+	analyser := NewAnaylser()
+	for _, diagnostic := range analyser.Analyse([]byte(source), "file:///equality.lox", log.New(io.Discard, "", 0)) {
+		if strings.HasPrefix(diagnostic.Message, "binary must be") {
+			t.Errorf("%d:%d: %s", diagnostic.Range.Start.Line, diagnostic.Range.Start.Character, diagnostic.Message)
+		}
+	}
+}
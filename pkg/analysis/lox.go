@@ -2,31 +2,52 @@ package analysis
 
 import (
 	"log"
-	"os"
 
 	"github.com/neet-007/lox_lsp_first/internal/lsp"
-	"github.com/neet-007/lox_lsp_first/pkg/rpc"
 )
 
 type Analyser struct {
-	hadError bool
-	uri      string
+	hadError    bool
+	uri         string
+	diagnostics []lsp.Diagnostic
+	documents   map[string]*Document
+	settings    Settings
+	skipIndex   bool
+
+	// What the document being analysed was parsed from, for the edits of
+	// the fixes offered for its diagnostics.
+	source []byte
+	tokens []Token
+	spans  map[any]Span
+	fixes  []quickFix
 }
 
 func NewAnaylser() *Analyser {
 	return &Analyser{
-		hadError: true,
-		uri:      "",
+		hadError:    true,
+		uri:         "",
+		diagnostics: []lsp.Diagnostic{},
+		documents:   map[string]*Document{},
+		settings:    DefaultSettings(),
+		spans:       map[any]Span{},
+		fixes:       []quickFix{},
 	}
 }
 
-func (analyser *Analyser) Analyse(source []byte, uri string, logger *log.Logger) {
+func (analyser *Analyser) Analyse(source []byte, uri string, logger *log.Logger) []lsp.Diagnostic {
 	analyser.uri = uri
+	analyser.hadError = false
+	analyser.diagnostics = []lsp.Diagnostic{}
+	analyser.source = source
+	analyser.fixes = []quickFix{}
+
 	scanner := NewScanner(source, analyser)
 
 	tokens := scanner.Scan()
+	analyser.tokens = tokens
 
 	parser := NewParser(tokens, analyser)
+	analyser.spans = parser.Spans()
 
 	statements := parser.Parse()
 
@@ -34,45 +55,69 @@ func (analyser *Analyser) Analyse(source []byte, uri string, logger *log.Logger)
 
 	resolver.Resolve(statements)
 
+	analyser.checkUndefined(statements, resolver)
+	analyser.checkArity(statements, resolver)
+	analyser.checkUnused(statements, resolver)
+	analyser.checkFlow(statements, analyser.spans, resolver)
+
+	inference := NewInference(resolver, analyser)
+	inference.Infer(statements)
+	analyser.checkInheritance(statements, resolver, inference)
+
+	fields := NewFields(resolver, inference)
+	fields.Collect(statements)
+	analyser.checkFields(fields)
+	analyser.checkMethods(statements, resolver, fields)
+
+	analyser.documents[uri] = NewDocument(uri, source, tokens, statements, resolver, inference, fields, analyser.spans, analyser.quickFixes())
+
 	interpreter := NewInterpreter(resolver.locals, analyser)
 	interpreter.Interpert(statements)
+
+	return analyser.diagnostics
 }
 
-func (analyser *Analyser) Error(token Token, message string) {
+// Error reports message at token. The diagnostic it returns can be filled
+// in further until the next one is reported.
+func (analyser *Analyser) Error(token Token, message string) *lsp.Diagnostic {
 	analyser.hadError = true
-	lexeme := ""
-	if token.Lexeme != "@" {
-		lexeme = token.Lexeme
+	return analyser.report(token, lsp.DiagnosticSeverityError, message)
+}
+
+// Warning reports something that is allowed but likely a mistake.
+func (analyser *Analyser) Warning(token Token, message string) *lsp.Diagnostic {
+	return analyser.report(token, lsp.DiagnosticSeverityWarning, message)
+}
+
+// Unnecessary warns about code that has no effect; editors fade it out.
+func (analyser *Analyser) Unnecessary(token Token, message string) *lsp.Diagnostic {
+	diagnostic := analyser.report(token, lsp.DiagnosticSeverityWarning, message)
+	diagnostic.Tags = []int{lsp.DiagnosticTagUnnecessary}
+	return diagnostic
+}
+
+// UnnecessarySpan is Unnecessary for code that spans several tokens.
+func (analyser *Analyser) UnnecessarySpan(span Span, message string) {
+	diagnostic := analyser.reportRange(span.Range(), span.Start.Lexeme, lsp.DiagnosticSeverityWarning, message)
+	diagnostic.Tags = []int{lsp.DiagnosticTagUnnecessary}
+}
+
+func (analyser *Analyser) report(token Token, severity int, message string) *lsp.Diagnostic {
+	return analyser.reportRange(tokenRange(token), token.Lexeme, severity, message)
+}
+
+func (analyser *Analyser) reportRange(range_ lsp.Range, lexeme string, severity int, message string) *lsp.Diagnostic {
+	if lexeme == "@" {
+		lexeme = ""
 	}
 
 	diagnostic := lsp.NewDiagnostic(
-		lsp.Range{
-			Start: lsp.Position{
-				Line:      token.StartLine,
-				Character: token.StartChar,
-			},
-			End: lsp.Position{
-				Line:      token.StartLine,
-				Character: token.EndChar,
-			},
-		},
-		1,
+		range_,
+		severity,
 		lexeme,
 		message,
 	)
 
-	reply := rpc.EncodeMessage(lsp.PublishDiagnosticsNotification{
-		Notification: lsp.Notification{
-			RPC:    "2.0",
-			Method: "textDocument/publishDiagnostics",
-		},
-		Params: lsp.PublishDiagnosticsParams{
-			URI:         analyser.uri,
-			Diagnostics: []lsp.Diagnostic{diagnostic},
-		},
-	})
-
-	if _, err := os.Stdout.Write([]byte(reply)); err != nil {
-
-	}
+	analyser.diagnostics = append(analyser.diagnostics, diagnostic)
+	return &analyser.diagnostics[len(analyser.diagnostics)-1]
 }
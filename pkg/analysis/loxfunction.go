@@ -1,12 +1,12 @@
 package analysis
 
 type LoxFunction struct {
-	declaration   Function
+	declaration   *Function
 	clouser       *Environment
 	isInitializer bool
 }
 
-func NewLoxFunction(declaration Function, clouser *Environment, isInitializer bool) *LoxFunction {
+func NewLoxFunction(declaration *Function, clouser *Environment, isInitializer bool) *LoxFunction {
 	return &LoxFunction{
 		declaration:   declaration,
 		clouser:       clouser,
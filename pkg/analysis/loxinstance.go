@@ -22,7 +22,7 @@ func (instance *LoxInstance) get(name Token) any {
 		return method.Bind(instance)
 	}
 
-	//!TDOD error
+	// Reading a field that is never assigned is reported by checkFields.
 	return nil
 }
 
@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// checkMethods warns about methods declared twice in a class, methods that
+// take a different number of parameters than the ones they override, and
+// methods and fields of the same name, where the field hides the method
+// once it is assigned. It also checks the arguments of init called directly
+// on an instance of a known class.
+func (analyser *Analyser) checkMethods(statements []Stmt, resolver *Resolver, fields *Fields) {
+	for _, class := range fields.classes {
+		declared := map[string]*Function{}
+		for _, method := range class.Methods {
+			if method.Name.Lexeme == "" {
+				continue
+			}
+			if first, ok := declared[method.Name.Lexeme]; ok {
+				analyser.related(analyser.Warning(method.Name, fmt.Sprintf(
+					"method '%s' is already declared in '%s'; this declaration replaces it",
+					method.Name.Lexeme, class.Name.Lexeme)), first.Name)
+			}
+			declared[method.Name.Lexeme] = method
+		}
+
+		for _, method := range class.Methods {
+			if declared[method.Name.Lexeme] != method {
+				continue
+			}
+			analyser.checkOverride(class, method, resolver)
+			if field, ok := fields.Lookup(class, method.Name.Lexeme); ok {
+				analyser.related(analyser.Warning(method.Name, fmt.Sprintf(
+					"method '%s' is hidden by a field of the same name assigned on instances of '%s'",
+					method.Name.Lexeme, field.Class.Name.Lexeme)), field.Name)
+			}
+		}
+
+		// A field can also hide a method the class inherits.
+		for _, field := range fields.Of(class) {
+			if field.Class != class || declared[field.Name.Lexeme] != nil {
+				continue
+			}
+			if owner, method := findMethod(superclassOf(class, resolver), field.Name.Lexeme, resolver); method != nil {
+				analyser.related(analyser.Warning(field.Name, fmt.Sprintf("field '%s' hides the method '%s.%s'",
+					field.Name.Lexeme, owner.Name.Lexeme, method.Name.Lexeme)), method.Name)
+			}
+		}
+	}
+
+	WalkStatements(statements, func(node any) bool {
+		call, ok := node.(*Call)
+		if !ok {
+			return true
+		}
+		get, ok := call.Callee.(*Get)
+		if !ok || get.Name.Lexeme != "init" {
+			return true
+		}
+		// Calls on this are checked with the other methods by checkArity.
+		if _, ok := get.Object.(*This); ok {
+			return true
+		}
+
+		class, ok := fields.ObjectClass(get)
+		if !ok {
+			return true
+		}
+		if owner, initializer := findMethod(class, "init", resolver); initializer != nil {
+			analyser.checkArguments(call, get.Name, initializer.Name,
+				functionSignature(owner.Name.Lexeme+".init", initializer), len(initializer.Params))
+		}
+		return true
+	})
+}
+
+// checkOverride warns when method takes a different number of parameters
+// than the method of a superclass that it overrides. Initializers are
+// left alone, since a subclass often needs other arguments to be made.
+func (analyser *Analyser) checkOverride(class *Class, method *Function, resolver *Resolver) {
+	if method.Name.Lexeme == "init" {
+		return
+	}
+
+	owner, overridden := findMethod(superclassOf(class, resolver), method.Name.Lexeme, resolver)
+	if overridden == nil || owner == class || len(overridden.Params) == len(method.Params) {
+		return
+	}
+
+	noun := "parameters"
+	if len(method.Params) == 1 {
+		noun = "parameter"
+	}
+	analyser.related(analyser.Warning(method.Name, fmt.Sprintf("%s overrides %s but takes %d %s instead of %d",
+		functionSignature(class.Name.Lexeme+"."+method.Name.Lexeme, method),
+		functionSignature(owner.Name.Lexeme+"."+overridden.Name.Lexeme, overridden),
+		len(method.Params), noun, len(overridden.Params))), overridden.Name)
+}
+
+// related points diagnostic at the declaration named declared.
+func (analyser *Analyser) related(diagnostic *lsp.Diagnostic, declared Token) {
+	diagnostic.RelatedInformation = []lsp.DiagnosticRelatedInformation{
+		{
+			Location: lsp.Location{URI: analyser.uri, Range: tokenRange(declared)},
+			Message:  fmt.Sprintf("'%s' is declared here", declared.Lexeme),
+		},
+	}
+}
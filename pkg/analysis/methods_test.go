@@ -0,0 +1,93 @@
+package analysis
+
+import "testing"
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "overrides",
+			source: `class Shape {
+  init(name) {}
+  area() {}
+  scale(factor) {}
+}
+class Square < Shape {
+  init(side, unit) {}
+  area(unit) {}
+  scale(by) {}
+}
+class Cube < Square {
+  scale() {}
+}
+`,
+			want: `7:2-6: Square.area(unit) overrides Shape.area() but takes 1 parameter instead of 0
+11:2-7: Cube.scale() overrides Square.scale(by) but takes 0 parameters instead of 1
+`,
+		},
+		{
+			name: "declared twice",
+			source: `class Greeter {
+  greet() {}
+  wave() {}
+  greet(name) {}
+}
+Greeter().greet("you");
+`,
+			want: `3:2-7: method 'greet' is already declared in 'Greeter'; this declaration replaces it
+`,
+		},
+		{
+			name: "fields and methods of the same name",
+			source: `class Base {
+  size() {}
+}
+class Box < Base {
+  init() {
+    this.size = 1;
+    this.label = "box";
+  }
+  label() {}
+}
+`,
+			want: `8:2-7: method 'label' is hidden by a field of the same name assigned on instances of 'Box'
+5:9-13: field 'size' hides the method 'Base.size'
+`,
+		},
+		{
+			name: "init called directly",
+			source: `class Point {
+  init(x, y) {}
+  reset() {
+    this.init(0, 0);
+  }
+}
+var point = Point(1, 2);
+point.init(3);
+point.init(3, 4);
+`,
+			want: `7:6-10: Point.init(x, y) expects 2 arguments but got 1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			inference := NewInference(resolver, analyser)
+			inference.Infer(statements)
+			fields := NewFields(resolver, inference)
+			fields.Collect(statements)
+			analyser.diagnostics = nil
+
+			analyser.checkMethods(statements, resolver, fields)
+
+			if got := formatDiagnostics(analyser); got != test.want {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,12 @@ type Parser struct {
 	tokens      []Token
 	current     int
 	diagnostics []lsp.Diagnostic
+	lastError   *Token
+	spans       map[any]Span
+	// nesting counts the expressions being parsed, and failed is set once
+	// one of them reports an error.
+	nesting int
+	failed  bool
 }
 
 type ParseError struct {
@@ -23,14 +29,38 @@ func (e *ParseError) Error() string {
 }
 
 func NewParser(tokens []Token, analyser *Analyser) Parser {
+	if len(tokens) == 0 || tokens[len(tokens)-1].Type != EOF {
+		tokens = append(tokens, Token{Type: EOF})
+	}
+
 	return Parser{
 		analyser:    analyser,
 		tokens:      tokens,
 		current:     0,
 		diagnostics: []lsp.Diagnostic{},
+		spans:       map[any]Span{},
 	}
 }
 
+// Spans returns where each statement and expression parsed so far was
+// written.
+func (parser *Parser) Spans() map[any]Span {
+	return parser.spans
+}
+
+// mark records that node was written from the token at start up to the
+// previous one.
+func (parser *Parser) mark(node any, start int) {
+	if parser.current > start && start < len(parser.tokens) {
+		parser.spans[node] = Span{Start: parser.tokens[start], End: *parser.previous()}
+	}
+}
+
+func (parser *Parser) marked(expr Expr, start int) Expr {
+	parser.mark(expr, start)
+	return expr
+}
+
 func (parser *Parser) Parse() []Stmt {
 	statments := []Stmt{}
 
@@ -42,104 +72,80 @@ func (parser *Parser) Parse() []Stmt {
 }
 
 func (parser *Parser) declaration() Stmt {
+	start := parser.current
+
+	var stmt Stmt
+	var err error
+
 	if parser.match(VAR) {
-		stmt, err := parser.varDeclaration()
-		if err != nil {
-			if _, ok := err.(*ParseError); ok {
-				parser.synchronize()
-				return nil
-			}
-			panic(err)
-		}
-		return stmt
+		stmt, err = parser.varDeclaration()
+	} else if parser.match(CLASS) {
+		stmt, err = parser.classDeclaration()
+	} else if parser.match(FUN) {
+		stmt, err = parser.function("function")
+	} else {
+		stmt, err = parser.statement()
 	}
 
-	if parser.match(CLASS) {
-		stmt, err := parser.classDeclaration()
-		if err != nil {
-			if _, ok := err.(*ParseError); ok {
-				parser.synchronize()
-				return nil
-			}
-			panic(err)
-		}
-		return stmt
-	}
-	if parser.match(FUN) {
-		stmt, err := parser.function("function")
-		if err != nil {
-			if _, ok := err.(*ParseError); ok {
-				parser.synchronize()
-				return nil
-			}
-			panic(err)
+	if err != nil {
+		if _, ok := err.(*ParseError); !ok {
+			parser.error(*parser.peek(), err.Error())
 		}
-		return stmt
+		parser.synchronize()
+		return nil
 	}
 
-	stmt, err := parser.statement()
-	if err != nil {
-		if _, ok := err.(*ParseError); ok {
-			parser.synchronize()
-			return nil
+	// Nothing was parsed when the token cannot start a statement at all. It
+	// has been reported as a missing expression, so skip it and whatever
+	// follows it up to something that can.
+	if parser.current == start {
+		parser.advance()
+		for !parser.isAtEnd() && !parser.atStatementStart() && !parser.atExpressionStart() &&
+			!parser.check(LEFT_BRACE) {
+			parser.advance()
 		}
-		panic(err)
+		return nil
 	}
+
+	parser.mark(stmt, start)
 	return stmt
 }
 
-func (parser *Parser) function(kind string) (Function, error) {
+func (parser *Parser) function(kind string) (*Function, error) {
+	start := parser.current
 	name, err := parser.consume(IDENTIFIER, fmt.Sprintf("Expect name for %s ", kind))
 	if err != nil {
-		return Function{}, err
+		return nil, err
 	}
 
-	_, err = parser.consume(LEFT_PAREN, fmt.Sprintf("Expect ( after name for %s ", kind))
-	if err != nil {
-		return Function{}, err
-	}
+	parser.expect(LEFT_PAREN, fmt.Sprintf("Expect ( after name for %s ", kind))
 
 	params := []Token{}
 	if !parser.check(RIGHT_PAREN) {
-		param, err := parser.consume(IDENTIFIER, fmt.Sprintf("Expect param for %s ", kind))
-		if err != nil {
-			return Function{}, err
-		}
-
-		params = append(params, *param)
-
-		for parser.match(COMMA) && !parser.isAtEnd() {
+		for {
 			if len(params) >= 256 {
-				return Function{}, &ParseError{
-					Code:    1,
-					Message: "cant have params for than 256",
-				}
+				parser.error(*parser.peek(), "cant have params for than 256")
 			}
-			param, err = parser.consume(IDENTIFIER, fmt.Sprintf("Expect param for %s ", kind))
-			if err != nil {
-				return Function{}, err
+
+			param, err := parser.consume(IDENTIFIER, fmt.Sprintf("Expect param for %s ", kind))
+			if err == nil {
+				params = append(params, *param)
 			}
 
-			params = append(params, *param)
+			if !parser.match(COMMA) {
+				break
+			}
 		}
 	}
 
-	_, err = parser.consume(RIGHT_PAREN, fmt.Sprintf("Expect ) after params for %s ", kind))
-	if err != nil {
-		return Function{}, err
-	}
+	parser.closeParen(fmt.Sprintf("Expect ) after params for %s ", kind))
+	parser.expect(LEFT_BRACE, fmt.Sprintf("Expect { before body for %s ", kind))
 
-	_, err = parser.consume(LEFT_BRACE, fmt.Sprintf("Expect { before body for %s ", kind))
-	if err != nil {
-		return Function{}, err
-	}
-
-	body, err := parser.block()
-	if err != nil {
-		return Function{}, err
-	}
+	body, end := parser.block()
 
-	return NewFunction(*name, params, body), nil
+	function := NewFunction(*name, params, body, end)
+	parser.mark(function, start)
+	return function, nil
 }
 
 func (parser *Parser) classDeclaration() (Stmt, error) {
@@ -148,37 +154,33 @@ func (parser *Parser) classDeclaration() (Stmt, error) {
 		return nil, err
 	}
 
-	var superclass Variable
+	var superclass *Variable
 	if parser.match(LESS) {
-		_, err = parser.consume(IDENTIFIER, "Expect superclass name after < ")
-		if err != nil {
-			return nil, err
+		superclassName, err := parser.consume(IDENTIFIER, "Expect superclass name after < ")
+		if err == nil {
+			superclass = NewVariable(*superclassName)
 		}
-		superclass = NewVariable(*parser.previous())
 	}
 
-	_, err = parser.consume(LEFT_BRACE, "Expect { before class body")
-	if err != nil {
-		return nil, err
-	}
+	parser.expect(LEFT_BRACE, "Expect { before class body")
 
-	methods := []Function{}
+	methods := []*Function{}
 
-	for !parser.check(RIGHT_BRACE) && !parser.isAtEnd() {
+	// A statement keyword cannot start a method, so the class body ends
+	// there even when its '}' is missing.
+	for !parser.check(RIGHT_BRACE) && !parser.isAtEnd() && !parser.atStatementStart() {
 		method, err := parser.function("method")
 		if err != nil {
-			return nil, err
+			parser.advance()
+			continue
 		}
 
 		methods = append(methods, method)
 	}
 
-	_, err = parser.consume(RIGHT_BRACE, "Expect } after class body")
-	if err != nil {
-		return nil, err
-	}
+	end := parser.expect(RIGHT_BRACE, "Expect } after class body")
 
-	return NewClass(*name, superclass, methods), nil
+	return NewClass(*name, superclass, methods, end), nil
 }
 
 func (parser *Parser) varDeclaration() (Stmt, error) {
@@ -189,21 +191,25 @@ func (parser *Parser) varDeclaration() (Stmt, error) {
 
 	var initializer Expr = nil
 	if parser.match(EQUAL) {
-		initializer, err = parser.expression()
-		if err != nil {
-			return nil, err
-		}
+		initializer = parser.expression()
 	}
 
-	_, err = parser.consume(SEMICOLON, "Expect ';' after variable declaration.")
-	if err != nil {
-		return nil, err
-	}
+	parser.expect(SEMICOLON, "Expect ';' after variable declaration.")
 
 	return NewVar(*name, initializer), nil
 }
 
 func (parser *Parser) statement() (Stmt, error) {
+	start := parser.current
+	stmt, err := parser.parseStatement()
+	if err == nil {
+		parser.mark(stmt, start)
+	}
+
+	return stmt, err
+}
+
+func (parser *Parser) parseStatement() (Stmt, error) {
 	if parser.match(FOR) {
 		return parser.forStatement()
 	}
@@ -220,58 +226,32 @@ func (parser *Parser) statement() (Stmt, error) {
 		return parser.whileStatement()
 	}
 	if parser.match(LEFT_BRACE) {
-		stmts, err := parser.block()
-		if err != nil {
-			return nil, err
-		}
-
-		return NewBlock(stmts), nil
+		start := *parser.previous()
+		stmts, end := parser.block()
+		return NewBlock(stmts, start, end), nil
 	}
 
-	expr, err := parser.expression()
-	if err != nil {
-		return nil, err
-	}
-	_, err = parser.consume(SEMICOLON, "Expect ';' after expression.")
-	if err != nil {
-		return nil, err
-	}
-
-	return NewExpression(expr), nil
+	return parser.expressionStatement()
 }
 
-func (parser *Parser) block() ([]Stmt, error) {
+// block parses the declarations up to the closing '}', keeping each one
+// that parsed even when the '}' never comes.
+func (parser *Parser) block() ([]Stmt, Token) {
 	stmts := []Stmt{}
 
 	for !parser.isAtEnd() && !parser.check(RIGHT_BRACE) {
-		stmt := parser.declaration()
-
-		stmts = append(stmts, stmt)
+		stmts = append(stmts, parser.declaration())
 	}
 
-	_, err := parser.consume(RIGHT_BRACE, "Expect '}' after block")
-	if err != nil {
-		return []Stmt{}, err
-	}
+	end := parser.expect(RIGHT_BRACE, "Expect '}' after block")
 
-	return stmts, nil
+	return stmts, end
 }
 
 func (parser *Parser) whileStatement() (Stmt, error) {
-	_, err := parser.consume(LEFT_PAREN, "Expect '(' before condition")
-	if err != nil {
-		return nil, err
-	}
-
-	condition, err := parser.expression()
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = parser.consume(RIGHT_PAREN, "Expect ')' after condition")
-	if err != nil {
-		return nil, err
-	}
+	parser.expect(LEFT_PAREN, "Expect '(' before condition")
+	condition := parser.expression()
+	parser.closeParen("Expect ')' after condition")
 
 	body, err := parser.statement()
 	if err != nil {
@@ -284,51 +264,27 @@ func (parser *Parser) whileStatement() (Stmt, error) {
 func (parser *Parser) returnStatement() (Stmt, error) {
 	token := parser.previous()
 	var value Expr = nil
-	var err error
 	if !parser.check(SEMICOLON) {
-		value, err = parser.expression()
-		if err != nil {
-			return nil, err
-		}
+		value = parser.expression()
 	}
 
-	_, err = parser.consume(SEMICOLON, "Expect ';' after expression.")
-	if err != nil {
-		return nil, err
-	}
+	parser.expect(SEMICOLON, "Expect ';' after expression.")
 
 	return NewReturn(*token, value), nil
 }
 
 func (parser *Parser) printStatement() (Stmt, error) {
-	value, err := parser.expression()
-	if err != nil {
-		return nil, err
-	}
+	value := parser.expression()
 
-	_, err = parser.consume(SEMICOLON, "Expect ';' after statement")
-	if err != nil {
-		return nil, err
-	}
+	parser.expect(SEMICOLON, "Expect ';' after statement")
 
 	return NewPrint(value), nil
 }
 
 func (parser *Parser) ifStatement() (Stmt, error) {
-	_, err := parser.consume(LEFT_PAREN, "Expect '(' before statement")
-	if err != nil {
-		return nil, err
-	}
-
-	condition, err := parser.expression()
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = parser.consume(RIGHT_PAREN, "Expect ')' before statement")
-	if err != nil {
-		return nil, err
-	}
+	parser.expect(LEFT_PAREN, "Expect '(' before statement")
+	condition := parser.expression()
+	parser.closeParen("Expect ')' before statement")
 
 	thenBranch, err := parser.statement()
 	if err != nil {
@@ -349,12 +305,11 @@ func (parser *Parser) ifStatement() (Stmt, error) {
 }
 
 func (parser *Parser) forStatement() (Stmt, error) {
-	_, err := parser.consume(LEFT_PAREN, "Expect '(' before initializer")
-	if err != nil {
-		return nil, err
-	}
+	keyword := *parser.previous()
+	parser.expect(LEFT_PAREN, "Expect '(' before initializer")
 
 	var initializer Stmt
+	var err error
 	if parser.match(SEMICOLON) {
 		initializer = nil
 	} else if parser.match(VAR) {
@@ -371,39 +326,32 @@ func (parser *Parser) forStatement() (Stmt, error) {
 
 	var condition Expr
 	if !parser.check(SEMICOLON) {
-		condition, err = parser.expression()
-		if err != nil {
-			return nil, err
-		}
+		condition = parser.expression()
 	}
 
-	_, err = parser.consume(SEMICOLON, "Expect ';' between for")
-	if err != nil {
-		return nil, err
-	}
+	parser.expect(SEMICOLON, "Expect ';' between for")
 
 	var incerment Expr
 	if !parser.check(RIGHT_PAREN) {
-		incerment, err = parser.expression()
-		if err != nil {
-			return nil, err
-		}
+		incerment = parser.expression()
 	}
 
-	_, err = parser.consume(RIGHT_PAREN, "Expect ')' between for")
-	if err != nil {
-		return nil, err
-	}
+	parser.closeParen("Expect ')' between for")
 
 	body, err := parser.statement()
 	if err != nil {
 		return nil, err
 	}
 
+	end := *parser.previous()
+
 	if incerment != nil {
-		body = NewBlock(
+		sequence := NewBlock(
 			[]Stmt{body, NewExpression(incerment)},
+			keyword, end,
 		)
+		sequence.Sequence = true
+		body = sequence
 	}
 
 	if condition == nil {
@@ -413,293 +361,235 @@ func (parser *Parser) forStatement() (Stmt, error) {
 	body = NewWhile(condition, body)
 
 	if initializer != nil {
-		body = NewBlock([]Stmt{initializer, body})
+		body = NewBlock([]Stmt{initializer, body}, keyword, end)
 	}
 
 	return body, nil
 }
 
 func (parser *Parser) expressionStatement() (Stmt, error) {
-	expr, err := parser.expression()
-	if err != nil {
-		return nil, err
-	}
+	expr := parser.expression()
 
-	parser.consume(SEMICOLON, "Expect ';' after expression.")
+	parser.expect(SEMICOLON, "Expect ';' after expression.")
 
 	return NewExpression(expr), nil
 }
 
-func (parser *Parser) expression() (Expr, error) {
-	return parser.assignment()
-}
+// The expression rules never fail: anything missing is reported and parsed
+// as a Missing node, so the statement around it survives.
 
-func (parser *Parser) assignment() (Expr, error) {
-	expr, err := parser.or()
-	if err != nil {
-		return nil, err
+// expression reports only the first error in an expression, so a run of
+// stray operators is one error rather than one for each missing operand.
+func (parser *Parser) expression() Expr {
+	if parser.nesting == 0 {
+		parser.failed = false
 	}
 
+	parser.nesting++
+	expr := parser.assignment()
+	parser.nesting--
+
+	return expr
+}
+
+func (parser *Parser) assignment() Expr {
+	start := parser.current
+	expr := parser.or()
+
 	if parser.match(EQUAL) {
 		equals := parser.previous()
-		value, err := parser.assignment()
-		if err != nil {
-			return nil, err
-		}
+		value := parser.assignment()
 
-		varExpr, varOk := expr.(Variable)
+		varExpr, varOk := expr.(*Variable)
 		if varOk {
 			name := varExpr.Name
-			return NewAssign(name, value), nil
+			return parser.marked(NewAssign(name, value), start)
 		}
 
-		getExpr, getOk := expr.(Get)
+		getExpr, getOk := expr.(*Get)
 		if getOk {
-			return NewSet(getExpr.Object, getExpr.Name, value), nil
+			return parser.marked(NewSet(getExpr.Object, getExpr.Name, value), start)
 		}
 
 		parser.error(*equals, "Invalid assignment target.")
 	}
 
-	return expr, nil
+	return expr
 }
 
-func (parser *Parser) or() (Expr, error) {
-	expr, err := parser.and()
-	if err != nil {
-		return nil, err
-	}
+// The binary and logical operators are left associative and chain: a - b - c
+// parses as (a - b) - c and a or b or c as (a or b) or c.
 
-	if parser.match(OR) {
-		operator := parser.previous()
-		right, err := parser.and()
-		if err != nil {
-			return nil, err
-		}
+func (parser *Parser) or() Expr {
+	start := parser.current
+	expr := parser.and()
 
-		return NewLogical(expr, *operator, right), nil
+	for parser.match(OR) {
+		operator := parser.previous()
+		right := parser.and()
+		expr = parser.marked(NewLogical(expr, *operator, right), start)
 	}
 
-	return expr, nil
+	return expr
 }
 
-func (parser *Parser) and() (Expr, error) {
-	expr, err := parser.equality()
-	if err != nil {
-		return nil, err
-	}
+func (parser *Parser) and() Expr {
+	start := parser.current
+	expr := parser.equality()
 
-	if parser.match(AND) {
+	for parser.match(AND) {
 		operator := parser.previous()
-		right, err := parser.equality()
-		if err != nil {
-			return nil, err
-		}
-
-		return NewLogical(expr, *operator, right), nil
+		right := parser.equality()
+		expr = parser.marked(NewLogical(expr, *operator, right), start)
 	}
 
-	return expr, nil
+	return expr
 }
 
-func (parser *Parser) equality() (Expr, error) {
-	expr, err := parser.comparission()
-	if err != nil {
-		return nil, err
-	}
+func (parser *Parser) equality() Expr {
+	start := parser.current
+	expr := parser.comparission()
 
-	if parser.match(EQUAL_EQUAL, BANG_EQUAL) {
+	for parser.match(EQUAL_EQUAL, BANG_EQUAL) {
 		operator := parser.previous()
-		rigth, err := parser.comparission()
-		if err != nil {
-			return nil, err
-		}
-
-		return NewBinary(expr, *operator, rigth), nil
+		rigth := parser.comparission()
+		expr = parser.marked(NewBinary(expr, *operator, rigth), start)
 	}
-	return expr, nil
+
+	return expr
 }
 
-func (parser *Parser) comparission() (Expr, error) {
-	expr, err := parser.term()
-	if err != nil {
-		return nil, err
-	}
+func (parser *Parser) comparission() Expr {
+	start := parser.current
+	expr := parser.term()
 
-	if parser.match(GREATER, GREATER_EQUAL, LESS, LESS_EQUAL) {
+	for parser.match(GREATER, GREATER_EQUAL, LESS, LESS_EQUAL) {
 		operator := parser.previous()
-		rigth, err := parser.term()
-		if err != nil {
-			return nil, err
-		}
-
-		return NewBinary(expr, *operator, rigth), nil
+		rigth := parser.term()
+		expr = parser.marked(NewBinary(expr, *operator, rigth), start)
 	}
-	return expr, nil
+
+	return expr
 }
 
-func (parser *Parser) term() (Expr, error) {
-	expr, err := parser.factor()
-	if err != nil {
-		return nil, err
-	}
+func (parser *Parser) term() Expr {
+	start := parser.current
+	expr := parser.factor()
 
-	if parser.match(MINUS, PLUS) {
+	for parser.match(MINUS, PLUS) {
 		operator := parser.previous()
-		rigth, err := parser.factor()
-		if err != nil {
-			return nil, err
-		}
-
-		return NewBinary(expr, *operator, rigth), nil
+		rigth := parser.factor()
+		expr = parser.marked(NewBinary(expr, *operator, rigth), start)
 	}
-	return expr, nil
+
+	return expr
 }
 
-func (parser *Parser) factor() (Expr, error) {
-	expr, err := parser.unary()
-	if err != nil {
-		return nil, err
-	}
+func (parser *Parser) factor() Expr {
+	start := parser.current
+	expr := parser.unary()
 
-	if parser.match(SLASH, STAR) {
+	for parser.match(SLASH, STAR) {
 		operator := parser.previous()
-		rigth, err := parser.unary()
-		if err != nil {
-			return nil, err
-		}
-
-		return NewBinary(expr, *operator, rigth), nil
+		rigth := parser.unary()
+		expr = parser.marked(NewBinary(expr, *operator, rigth), start)
 	}
-	return expr, nil
+
+	return expr
 }
 
-func (parser *Parser) unary() (Expr, error) {
+func (parser *Parser) unary() Expr {
+	start := parser.current
 	if parser.match(BANG, MINUS) {
 		operator := parser.previous()
-		right, err := parser.unary()
-		if err != nil {
-			return nil, err
-		}
+		right := parser.unary()
 
-		return NewUnary(*operator, right), nil
+		return parser.marked(NewUnary(*operator, right), start)
 	}
 
 	return parser.call()
 }
 
-func (parser *Parser) call() (Expr, error) {
-	expr, err := parser.primary()
-	if err != nil {
-		return nil, err
-	}
+func (parser *Parser) call() Expr {
+	start := parser.current
+	expr := parser.primary()
 
 	for {
 		if parser.match(LEFT_PAREN) {
-			expr, err = parser.finishCall(expr)
-			if err != nil {
-				return nil, err
-			}
+			expr = parser.marked(parser.finishCall(expr), start)
 		} else if parser.match(DOT) {
-			name, err := parser.consume(IDENTIFIER, "Expect proprety name after '.'")
-			if err != nil {
-				return nil, err
-			}
-			expr = NewGet(expr, *name)
+			name := parser.expect(IDENTIFIER, "Expect proprety name after '.'")
+			expr = parser.marked(NewGet(expr, name), start)
 		} else {
 			break
 		}
 	}
 
-	return expr, nil
+	return expr
 }
 
-func (parser *Parser) finishCall(callee Expr) (Expr, error) {
+func (parser *Parser) finishCall(callee Expr) Expr {
 	args := []Expr{}
 
 	if !parser.check(RIGHT_PAREN) {
-		expr, err := parser.expression()
-		if err != nil {
-			return nil, err
-		}
-		args = append(args, expr)
-
-		for !parser.isAtEnd() && parser.match(COMMA) {
+		for {
 			if len(args) >= 256 {
-				return nil, &ParseError{
-					Code:    1,
-					Message: "cant have more than 255 arguemnts ",
-				}
+				parser.error(*parser.peek(), "cant have more than 255 arguemnts ")
 			}
-			expr, err = parser.expression()
-			if err != nil {
-				return nil, err
+			args = append(args, parser.expression())
+
+			if !parser.match(COMMA) {
+				break
 			}
-			args = append(args, expr)
 		}
 	}
 
-	rightParen, err := parser.consume(RIGHT_PAREN, "Expect ')' after call")
-	if err != nil {
-		return nil, err
-	}
+	rightParen := parser.closeParen("Expect ')' after call")
 
-	return NewCall(callee, *rightParen, args), nil
+	return NewCall(callee, rightParen, args)
 }
 
-func (parser *Parser) primary() (Expr, error) {
+func (parser *Parser) primary() Expr {
+	start := parser.current
 	if parser.match(FALSE) {
-		return NewLiteral(false), nil
+		return parser.marked(NewLiteral(false), start)
 	}
 	if parser.match(TRUE) {
-		return NewLiteral(true), nil
+		return parser.marked(NewLiteral(true), start)
 	}
 	if parser.match(NIL) {
-		return NewLiteral(nil), nil
+		return parser.marked(NewLiteral(nil), start)
 	}
 
 	if parser.match(STRING, NUMBER) {
 		prev := *parser.previous()
-		return NewLiteral(prev.Literal), nil
+		return parser.marked(NewLiteral(prev.Literal), start)
 	}
 
 	if parser.match(SUPER) {
 		keyword := parser.previous()
-		_, err := parser.consume(DOT, "Expect '.' after 'super'.")
-		if err != nil {
-			return nil, err
-		}
-		method, err := parser.consume(IDENTIFIER,
-			"Expect superclass method name.")
-		if err != nil {
-			return nil, err
-		}
-		return NewSuper(*keyword, *method), nil
+		parser.expect(DOT, "Expect '.' after 'super'.")
+		method := parser.expect(IDENTIFIER, "Expect superclass method name.")
+		return parser.marked(NewSuper(*keyword, method), start)
 	}
 
 	if parser.match(THIS) {
-		return NewThis(*parser.previous()), nil
+		return parser.marked(NewThis(*parser.previous()), start)
 	}
 
 	if parser.match(IDENTIFIER) {
-		return NewVariable(*parser.previous()), nil
+		return parser.marked(NewVariable(*parser.previous()), start)
 	}
 
 	if parser.match(LEFT_PAREN) {
-		expr, err := parser.expression()
-		if err != nil {
-			return nil, err
-		}
-
-		_, err = parser.consume(RIGHT_PAREN, "Expect ')' after expression.")
-		if err != nil {
-			return nil, err
-		}
+		expr := parser.expression()
+		parser.closeParen("Expect ')' after expression.")
 
-		return NewGrouping(expr), nil
+		return parser.marked(NewGrouping(expr), start)
 	}
 
-	return nil, parser.error(*parser.peek(), "Expect expression.")
+	parser.error(*parser.peek(), "Expect expression.")
+	return NewMissing(*parser.peek())
 }
 
 func (parser *Parser) isAtEnd() bool {
@@ -716,6 +606,10 @@ func (parser *Parser) check(tokenType TokenType) bool {
 }
 
 func (parser *Parser) previous() *Token {
+	if parser.current == 0 {
+		return parser.peek()
+	}
+
 	return &parser.tokens[parser.current-1]
 }
 
@@ -737,15 +631,131 @@ func (parser *Parser) consume(tokenType TokenType, msg string) (*Token, error) {
 	return nil, err
 }
 
+// expect is consume for tokens the parser can do without: when the token
+// is absent it is reported and a zero-width token after the previous one
+// stands in for it.
+func (parser *Parser) expect(tokenType TokenType, msg string) Token {
+	if parser.check(tokenType) {
+		return *parser.advance()
+	}
+
+	diagnostic := parser.report(*parser.peek(), msg)
+	missing := parser.missing(tokenType)
+	parser.insertFix(diagnostic, missing)
+	return missing
+}
+
+// closeParen expects the ')' that ends a list or condition. Whatever is left
+// before it is skipped, stopping early at anything that ends the statement,
+// so one stray token does not take the rest of the statement with it.
+func (parser *Parser) closeParen(msg string) Token {
+	if parser.check(RIGHT_PAREN) {
+		return *parser.advance()
+	}
+
+	diagnostic := parser.report(*parser.peek(), msg)
+
+	depth := 0
+	for !parser.isAtEnd() && !parser.atStatementEnd() {
+		if parser.check(LEFT_PAREN) {
+			depth++
+		} else if parser.check(RIGHT_PAREN) {
+			if depth == 0 {
+				return *parser.advance()
+			}
+			depth--
+		}
+		parser.advance()
+	}
+
+	missing := parser.missing(RIGHT_PAREN)
+	parser.insertFix(diagnostic, missing)
+	return missing
+}
+
+// insertFix offers to write the ';' or ')' that missing stands in for.
+func (parser *Parser) insertFix(diagnostic *lsp.Diagnostic, missing Token) {
+	var text string
+	switch missing.Type {
+	case SEMICOLON:
+		text = ";"
+	case RIGHT_PAREN:
+		text = ")"
+	default:
+		return
+	}
+
+	if diagnostic != nil {
+		parser.analyser.fix(diagnostic, fmt.Sprintf("Insert '%s'", text), insertText(tokenRange(missing).Start, text))
+	}
+}
+
+func (parser *Parser) missing(tokenType TokenType) Token {
+	previous := parser.previous()
+	return Token{
+		Type:      tokenType,
+		Uri:       previous.Uri,
+		StartLine: previous.StartLine,
+		EndLine:   previous.StartLine,
+		StartChar: previous.EndChar,
+		EndChar:   previous.EndChar,
+	}
+}
+
+func (parser *Parser) atStatementStart() bool {
+	switch parser.peek().Type {
+	case CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN:
+		return true
+	}
+
+	return false
+}
+
+func (parser *Parser) atExpressionStart() bool {
+	switch parser.peek().Type {
+	case FALSE, TRUE, NIL, STRING, NUMBER, SUPER, THIS, IDENTIFIER, LEFT_PAREN, BANG, MINUS:
+		return true
+	}
+
+	return false
+}
+
+func (parser *Parser) atStatementEnd() bool {
+	switch parser.peek().Type {
+	case SEMICOLON, LEFT_BRACE, RIGHT_BRACE:
+		return true
+	}
+
+	return parser.atStatementStart()
+}
+
+// error reports a syntax error unless one was already reported at token,
+// which happens when several rules give up on the same token in turn.
 func (parser *Parser) error(token Token, msg string) error {
-	fmt.Printf("parsing error %s %v %v\n", msg, token.Lexeme, TokenNames[token.Type])
-	parser.analyser.Error(token, msg)
+	parser.report(token, msg)
 	return &ParseError{
 		Code:    1,
 		Message: msg,
 	}
 }
 
+// report reports msg at token, unless an error was just reported there or
+// in the expression being parsed. The diagnostic is nil then.
+func (parser *Parser) report(token Token, msg string) *lsp.Diagnostic {
+	if parser.lastError != nil && *parser.lastError == token {
+		return nil
+	}
+	if parser.nesting > 0 {
+		if parser.failed {
+			return nil
+		}
+		parser.failed = true
+	}
+
+	parser.lastError = &token
+	return parser.analyser.Error(token, msg)
+}
+
 func (parser *Parser) match(tokenTypes ...TokenType) bool {
 	for _, t := range tokenTypes {
 		if parser.check(t) {
@@ -758,29 +768,37 @@ func (parser *Parser) match(tokenTypes ...TokenType) bool {
 }
 
 func (parser *Parser) peek() *Token {
+	if parser.current >= len(parser.tokens) {
+		return &parser.tokens[len(parser.tokens)-1]
+	}
+
 	return &parser.tokens[parser.current]
 }
 
+// synchronize skips what is left of a declaration that could not be parsed.
+// It stops after a ';', or before a keyword that starts a declaration or
+// statement, or before a '}' so the block or class body around the
+// declaration still gets its end. A body that opens while skipping, like the
+// one of a class without a name, is skipped whole.
 func (parser *Parser) synchronize() {
-	parser.advance()
-
+	depth := 0
 	for !parser.isAtEnd() {
-		if parser.previous().Type == SEMICOLON {
+		if depth == 0 && (parser.atStatementStart() || parser.check(RIGHT_BRACE)) {
 			return
 		}
 
-		switch parser.peek().Type {
-		case CLASS:
-		case FUN:
-		case VAR:
-		case FOR:
-		case IF:
-		case WHILE:
-		case PRINT:
-		case RETURN:
-			return
+		switch parser.advance().Type {
+		case SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		case LEFT_BRACE:
+			depth++
+		case RIGHT_BRACE:
+			depth--
+			if depth == 0 {
+				return
+			}
 		}
-
-		parser.advance()
 	}
 }
@@ -0,0 +1,493 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// refactors returns the refactorings that apply to the selection: extracting
+// the expression or statements it covers, and inlining the variable it is
+// on.
+func (document *Document) refactors(selection lsp.Range) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	if action, ok := document.extractVariable(selection); ok {
+		actions = append(actions, action)
+	}
+	if action, ok := document.extractFunction(selection); ok {
+		actions = append(actions, action)
+	}
+	if action, ok := document.inlineVariable(selection.Start); ok {
+		actions = append(actions, action)
+	}
+
+	return actions
+}
+
+func (document *Document) refactor(title string, kind string, edits ...lsp.TextEdit) lsp.CodeAction {
+	return lsp.CodeAction{
+		Title: title,
+		Kind:  kind,
+		Edit: &lsp.WorkspaceEdit{
+			Changes: map[string][]lsp.TextEdit{document.Uri: edits},
+		},
+	}
+}
+
+// extractVariable declares a variable holding the selected expression
+// right before the statement it is in, and uses the variable instead.
+// Conditions of loops and the right operands of 'and' and 'or' are evaluated
+// a varying number of times, and so are the branches of an if without
+// braces and the conditions of its else ifs, so they are left alone.
+func (document *Document) extractVariable(selection lsp.Range) (lsp.CodeAction, bool) {
+	var selected Expr
+	WalkStatements(document.Statements, func(node any) bool {
+		if selected != nil {
+			return false
+		}
+		expr, ok := node.(Expr)
+		if !ok {
+			return true
+		}
+		if _, ok := expr.(*Missing); ok {
+			return true
+		}
+		if span, ok := document.spans[expr]; ok && document.covers(selection, span) {
+			selected = expr
+			return false
+		}
+		return true
+	})
+	if selected == nil {
+		return lsp.CodeAction{}, false
+	}
+
+	span := document.spans[selected]
+	start := span.Range().Start
+	stmt, ok := statementAt(document.Statements, start, document.spans)
+	if !ok {
+		return lsp.CodeAction{}, false
+	}
+	switch stmt := stmt.(type) {
+	case *Block, *While:
+		return lsp.CodeAction{}, false
+	case *If:
+		if !within(selected, stmt.Condition) {
+			return lsp.CodeAction{}, false
+		}
+	}
+	statementStart, _ := statementSpan(stmt, document.spans)
+	if !document.startsStatement(statementStart.Start) {
+		return lsp.CodeAction{}, false
+	}
+
+	conditional := false
+	Walk(stmt, func(node any) bool {
+		if logical, ok := node.(*Logical); ok && within(selected, logical.Right) {
+			conditional = true
+		}
+		return !conditional
+	})
+	if conditional {
+		return lsp.CodeAction{}, false
+	}
+
+	name := document.freshName("value")
+	before := tokenRange(statementStart.Start).Start
+	separator := " "
+	if indent := indentation(document.Source, before.Line); len(indent) == before.Character {
+		separator = "\n" + indent
+	}
+	declaration := fmt.Sprintf("var %s = %s;%s", name, document.text(span.Range()), separator)
+
+	return document.refactor("Extract variable", lsp.CodeActionKindRefactorExtract,
+		insertText(before, declaration),
+		lsp.TextEdit{Range: span.Range(), NewText: name},
+	), true
+}
+
+// startsStatement reports whether a declaration can go right before the
+// statement that starts with start: it follows a statement or a brace, not
+// the parentheses of a loop or an if whose body has no braces.
+func (document *Document) startsStatement(start Token) bool {
+	for i, token := range document.Tokens {
+		if token.StartLine != start.StartLine || token.StartChar != start.StartChar {
+			continue
+		}
+		if i == 0 {
+			return true
+		}
+		switch document.Tokens[i-1].Type {
+		case SEMICOLON, LEFT_BRACE, RIGHT_BRACE:
+			return true
+		}
+		return false
+	}
+
+	return false
+}
+
+// extractFunction moves the selected statements into a new function
+// declared before the top level statement they are in, and calls it in
+// their place. The locals they use from around them become its parameters.
+// A variable that the statements assign, or declare for the code after
+// them, is returned; there can be only one.
+func (document *Document) extractFunction(selection lsp.Range) (lsp.CodeAction, bool) {
+	statements, ok := document.selectedStatements(document.Statements, selection)
+	if !ok {
+		return lsp.CodeAction{}, false
+	}
+	span := Span{Start: document.spans[statements[0]].Start, End: document.spans[statements[len(statements)-1]].End}
+	inside := func(token Token) bool {
+		return contains(span.Range(), tokenRange(token).Start)
+	}
+
+	// What the statements refer to by this, super or return belongs to the
+	// function they are in, unless it is in a function or class they declare.
+	nested := map[*Return]bool{}
+	WalkStatements(statements, func(node any) bool {
+		if function, ok := node.(*Function); ok {
+			WalkStatements(function.Body, func(node any) bool {
+				if stmt, ok := node.(*Return); ok {
+					nested[stmt] = true
+				}
+				return true
+			})
+		}
+		return true
+	})
+	movable := true
+	WalkStatements(statements, func(node any) bool {
+		switch node := node.(type) {
+		case *Class:
+			return false
+		case *This, *Super:
+			movable = false
+		case *Return:
+			movable = nested[node]
+		}
+		return movable
+	})
+	if !movable {
+		return lsp.CodeAction{}, false
+	}
+
+	params := []*Symbol{}
+	isParam := map[*Symbol]bool{}
+	modified := []*Symbol{}
+	isModified := map[*Symbol]bool{}
+	WalkStatements(statements, func(node any) bool {
+		expr, ok := node.(Expr)
+		if !ok {
+			return true
+		}
+		symbol, ok := document.resolver.SymbolOf(expr)
+		if !ok || symbol.Scope.Kind == GLOBAL_SCOPE || inside(symbol.Token) {
+			return true
+		}
+
+		if !isParam[symbol] {
+			isParam[symbol] = true
+			params = append(params, symbol)
+		}
+		if _, ok := expr.(*Assign); ok && !isModified[symbol] {
+			isModified[symbol] = true
+			modified = append(modified, symbol)
+		}
+		return true
+	})
+
+	// Declarations used after the statements must come out of the function.
+	escaping := []*Symbol{}
+	for _, stmt := range statements {
+		var name Token
+		switch stmt := stmt.(type) {
+		case *Var:
+			name = stmt.Name
+		case *Function:
+			name = stmt.Name
+		case *Class:
+			name = stmt.Name
+		default:
+			continue
+		}
+
+		symbol, ok := document.resolver.DeclaredSymbol(name)
+		if !ok {
+			continue
+		}
+		for _, reference := range symbol.References {
+			if referenced, ok := referenceName(reference); ok && !inside(referenced) {
+				if _, ok := stmt.(*Var); !ok {
+					return lsp.CodeAction{}, false
+				}
+				escaping = append(escaping, symbol)
+				break
+			}
+		}
+	}
+	if len(modified)+len(escaping) > 1 {
+		return lsp.CodeAction{}, false
+	}
+
+	var top Span
+	found := false
+	for _, stmt := range document.Statements {
+		if top, found = statementSpan(stmt, document.spans); found && contains(top.Range(), span.Range().Start) {
+			break
+		}
+		found = false
+	}
+	if !found {
+		return lsp.CodeAction{}, false
+	}
+
+	name := document.freshName("extracted")
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.Name)
+	}
+	call := fmt.Sprintf("%s(%s)", name, strings.Join(names, ", "))
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "fun %s(%s) {\n", name, strings.Join(names, ", "))
+	base := indentation(document.Source, span.Start.StartLine)
+	for _, line := range strings.Split(document.text(span.Range()), "\n") {
+		if line = strings.TrimPrefix(line, base); line != "" {
+			line = "  " + line
+		}
+		body.WriteString(line + "\n")
+	}
+
+	switch {
+	case len(modified) == 1:
+		fmt.Fprintf(&body, "  return %s;\n", modified[0].Name)
+		call = fmt.Sprintf("%s = %s;", modified[0].Name, call)
+	case len(escaping) == 1:
+		fmt.Fprintf(&body, "  return %s;\n", escaping[0].Name)
+		call = fmt.Sprintf("var %s = %s;", escaping[0].Name, call)
+	default:
+		call += ";"
+	}
+	body.WriteString("}\n\n")
+
+	insertion := lsp.Position{Line: top.Start.StartLine}
+	if insertion == span.Range().Start {
+		return document.refactor("Extract function", lsp.CodeActionKindRefactorExtract,
+			lsp.TextEdit{Range: span.Range(), NewText: body.String() + call},
+		), true
+	}
+
+	return document.refactor("Extract function", lsp.CodeActionKindRefactorExtract,
+		insertText(insertion, body.String()),
+		lsp.TextEdit{Range: span.Range(), NewText: call},
+	), true
+}
+
+// selectedStatements finds the statements of a block or body that the
+// selection covers.
+func (document *Document) selectedStatements(statements []Stmt, selection lsp.Range) ([]Stmt, bool) {
+	for i := range statements {
+		first, ok := document.spans[statements[i]]
+		if !ok {
+			continue
+		}
+		for j := i; j < len(statements); j++ {
+			last, ok := document.spans[statements[j]]
+			if !ok {
+				break
+			}
+			if document.covers(selection, Span{Start: first.Start, End: last.End}) {
+				return statements[i : j+1], true
+			}
+		}
+	}
+
+	for _, stmt := range statements {
+		for _, inner := range innerStatements(stmt) {
+			if found, ok := document.selectedStatements(inner, selection); ok {
+				return found, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// inlineVariable replaces the uses of the variable declared or used at
+// position with its initializer, and removes the declaration. The
+// initializer must give the same value wherever the variable is used: it
+// may only read variables that are never assigned or declared again, and
+// they must not be shadowed where it is used. The variable must not be
+// declared again either.
+func (document *Document) inlineVariable(position lsp.Position) (lsp.CodeAction, bool) {
+	token, ok := document.tokenAt(position)
+	if !ok {
+		return lsp.CodeAction{}, false
+	}
+	symbol, ok := document.resolver.DeclaredSymbol(token)
+	if !ok {
+		reference, ok := document.referenceAt(token)
+		if !ok {
+			return lsp.CodeAction{}, false
+		}
+		if symbol, ok = document.resolver.SymbolOf(reference); !ok {
+			return lsp.CodeAction{}, false
+		}
+	}
+
+	stmt, ok := symbol.Node.(*Var)
+	if !ok || symbol.Kind != VARIABLE_DECLARATION || stmt.Initializer == nil || len(symbol.References) == 0 || !constant(symbol) {
+		return lsp.CodeAction{}, false
+	}
+	declaration, ok := document.spans[stmt]
+	initializer, spanned := document.spans[stmt.Initializer]
+	if !ok || !spanned {
+		return lsp.CodeAction{}, false
+	}
+	for _, reference := range symbol.References {
+		if _, ok := reference.(*Variable); !ok {
+			return lsp.CodeAction{}, false
+		}
+	}
+
+	reads := []*Variable{}
+	stable := true
+	Walk(stmt.Initializer, func(node any) bool {
+		switch node := node.(type) {
+		case *Literal, *Grouping, *Unary, *Binary, *Logical, *This:
+		case *Variable:
+			read, ok := document.resolver.SymbolOf(node)
+			stable = ok && read != symbol && constant(read)
+			reads = append(reads, node)
+		default:
+			stable = false
+		}
+		return stable
+	})
+	if !stable {
+		return lsp.CodeAction{}, false
+	}
+
+	operands := document.operands()
+	text := document.text(initializer.Range())
+	edits := []lsp.TextEdit{}
+	for _, reference := range symbol.References {
+		name := reference.(*Variable).Name
+		scope := document.resolver.Scopes().ScopeAt(tokenRange(name).Start)
+		for _, read := range reads {
+			visible, ok := scope.Lookup(read.Name.Lexeme)
+			if declared, _ := document.resolver.SymbolOf(read); !ok || visible != declared {
+				return lsp.CodeAction{}, false
+			}
+		}
+
+		replacement := text
+		switch stmt.Initializer.(type) {
+		case *Unary, *Binary, *Logical:
+			if operands[reference] {
+				replacement = "(" + text + ")"
+			}
+		}
+		edits = append(edits, lsp.TextEdit{Range: tokenRange(name), NewText: replacement})
+	}
+	edits = append(edits, lsp.TextEdit{Range: removal(document.Source, declaration)})
+
+	return document.refactor(fmt.Sprintf("Inline variable '%s'", symbol.Name), lsp.CodeActionKindRefactorInline, edits...), true
+}
+
+func isAssigned(symbol *Symbol) bool {
+	for _, reference := range symbol.References {
+		if _, ok := reference.(*Assign); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// operands returns the expressions that are operands of an operator, a
+// property access or a call, where an expression with operators of its own
+// needs parentheses.
+func (document *Document) operands() map[Expr]bool {
+	operands := map[Expr]bool{}
+	WalkStatements(document.Statements, func(node any) bool {
+		switch node := node.(type) {
+		case *Binary:
+			operands[node.Left], operands[node.Right] = true, true
+		case *Logical:
+			operands[node.Left], operands[node.Right] = true, true
+		case *Unary:
+			operands[node.Right] = true
+		case *Get:
+			operands[node.Object] = true
+		case *Set:
+			operands[node.Object] = true
+		case *Call:
+			operands[node.Callee] = true
+		}
+		return true
+	})
+
+	return operands
+}
+
+// freshName returns base, or base with a number after it, so that it is
+// not a name the document uses anywhere.
+func (document *Document) freshName(base string) string {
+	used := map[string]bool{}
+	for _, token := range document.Tokens {
+		used[token.Lexeme] = true
+	}
+
+	name := base
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+
+	return name
+}
+
+// covers reports whether the selection is span, give or take white space.
+func (document *Document) covers(selection lsp.Range, span Span) bool {
+	from, to := document.offset(selection.Start), document.offset(selection.End)
+	start, end := document.offset(span.Range().Start), document.offset(span.Range().End)
+	if from > start || end > to {
+		return false
+	}
+
+	return len(bytes.TrimSpace(document.Source[from:start])) == 0 && len(bytes.TrimSpace(document.Source[end:to])) == 0
+}
+
+// text returns the source within range_.
+func (document *Document) text(range_ lsp.Range) string {
+	return string(document.Source[document.offset(range_.Start):document.offset(range_.End)])
+}
+
+// offset returns the index into the source of position. A position before
+// the start of the source is its start, one past its end its end.
+func (document *Document) offset(position lsp.Position) int {
+	offset := 0
+	for line := 0; line < position.Line; line++ {
+		next := bytes.IndexByte(document.Source[offset:], '\n')
+		if next < 0 {
+			return len(document.Source)
+		}
+		offset += next + 1
+	}
+
+	return max(0, min(offset+position.Character, len(document.Source)))
+}
+
+// within reports whether expr is part of outer.
+func within(expr Expr, outer Expr) bool {
+	found := false
+	Walk(outer, func(node any) bool {
+		found = found || node == expr
+		return !found
+	})
+
+	return found
+}
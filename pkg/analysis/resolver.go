@@ -1,5 +1,7 @@
 package analysis
 
+import "github.com/neet-007/lox_lsp_first/internal/lsp"
+
 type FunctionType int
 type ClassType int
 
@@ -16,31 +18,97 @@ const (
 	SUBCLASS
 )
 
+// Resolver binds every name to its declaration. Besides the stack of scopes
+// it walks with, it builds a tree of them that outlives the walk, rooted at
+// the global scope.
 type Resolver struct {
-	analyser        *Analyser
-	scopes          []map[string]bool
-	currentFunction FunctionType
-	currrntClass    ClassType
-	locals          map[Expr]int
+	analyser         *Analyser
+	scopes           []map[string]bool
+	scopeTree        []*Scope
+	global           *Scope
+	currentFunction  FunctionType
+	currrntClass     ClassType
+	locals           map[Expr]int
+	globalReferences []Expr
+	bindings         map[Expr]*Symbol
+	// initializing is the global whose first declaration is being
+	// initialized, which cannot be read yet.
+	initializing string
 }
 
 func NewResolver(analyser *Analyser) *Resolver {
 	return &Resolver{
-		analyser:        analyser,
-		scopes:          []map[string]bool{},
-		currentFunction: NONE_FUNCTION,
-		currrntClass:    NONE_CLASS,
-		locals:          map[Expr]int{},
+		analyser:         analyser,
+		scopes:           []map[string]bool{},
+		scopeTree:        []*Scope{},
+		global:           NewScope(GLOBAL_SCOPE, nil, Token{}, Token{}),
+		currentFunction:  NONE_FUNCTION,
+		currrntClass:     NONE_CLASS,
+		locals:           map[Expr]int{},
+		globalReferences: []Expr{},
+		bindings:         map[Expr]*Symbol{},
+	}
+}
+
+// Scopes returns the global scope, the root of the scope tree.
+func (resolver *Resolver) Scopes() *Scope {
+	return resolver.global
+}
+
+// SymbolOf returns the symbol that a Variable, Assign, This or Super
+// expression refers to.
+func (resolver *Resolver) SymbolOf(expr Expr) (*Symbol, bool) {
+	symbol, ok := resolver.bindings[expr]
+	return symbol, ok
+}
+
+// DeclaredSymbol returns the symbol declared by the name token of a
+// declaration. A name declared twice in a scope is one variable, the first
+// symbol.
+func (resolver *Resolver) DeclaredSymbol(name Token) (*Symbol, bool) {
+	position := lsp.Position{Line: name.StartLine, Character: name.StartChar}
+	for scope := resolver.global.ScopeAt(position); scope != nil; scope = scope.Parent {
+		for _, symbol := range scope.Symbols {
+			if symbol.Token == name {
+				first, ok := scope.names[name.Lexeme]
+				return first, ok
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Declaration returns the token that declared the name expr refers to.
+func (resolver *Resolver) Declaration(expr Expr) (Token, bool) {
+	if symbol, ok := resolver.bindings[expr]; ok {
+		return symbol.Token, true
 	}
+
+	return Token{}, false
 }
 
+// Resolve resolves a program. References to globals are bound once all of
+// it has been seen, since a function may use a global declared after it.
 func (resolver *Resolver) Resolve(statemnts []Stmt) {
+	resolver.resolveStatements(statemnts)
+
+	for _, expr := range resolver.globalReferences {
+		name, _ := referenceName(expr)
+		if symbol, ok := resolver.global.names[name.Lexeme]; ok {
+			resolver.bind(expr, symbol)
+		}
+	}
+	resolver.globalReferences = []Expr{}
+}
+
+func (resolver *Resolver) resolveStatements(statemnts []Stmt) {
 	for _, stmt := range statemnts {
 		resolver.resolveStmt(stmt)
 	}
 }
 
-func (resolver *Resolver) VisitSuperExpr(expr Super) any {
+func (resolver *Resolver) VisitSuperExpr(expr *Super) any {
 	if resolver.currrntClass == NONE_CLASS {
 		resolver.analyser.Error(expr.Keyword, "can not use 'super' outside class")
 		return nil
@@ -55,7 +123,7 @@ func (resolver *Resolver) VisitSuperExpr(expr Super) any {
 	return nil
 }
 
-func (resolver *Resolver) VisitThisExpr(expr This) any {
+func (resolver *Resolver) VisitThisExpr(expr *This) any {
 	if resolver.currrntClass == NONE_CLASS {
 		resolver.analyser.Error(expr.Keyword, "can not use 'this' outside class")
 		return nil
@@ -65,24 +133,28 @@ func (resolver *Resolver) VisitThisExpr(expr This) any {
 	return nil
 }
 
-func (resolver *Resolver) resolveFunction(stmt Function, kind FunctionType) {
+func (resolver *Resolver) resolveFunction(stmt *Function, kind FunctionType) {
 	enclosing := resolver.currentFunction
 	resolver.currentFunction = kind
 
-	resolver.beginScope()
+	scopeKind := FUNCTION_SCOPE
+	if kind == METHOD || kind == INITIALIZER {
+		scopeKind = METHOD_SCOPE
+	}
+	resolver.beginScope(resolver.newScope(scopeKind, stmt.Name, stmt.End))
 
 	for _, param := range stmt.Params {
-		resolver.declare(param)
+		resolver.declare(param, PARAMETER_DECLARATION, stmt)
 		resolver.define(param)
 	}
 
-	resolver.Resolve(stmt.Body)
+	resolver.resolveStatements(stmt.Body)
 	resolver.endScope()
 
 	resolver.currentFunction = enclosing
 }
 
-func (resolver *Resolver) VisitReturnStmt(stmt Return) any {
+func (resolver *Resolver) VisitReturnStmt(stmt *Return) any {
 	if resolver.currentFunction == NONE_FUNCTION {
 		resolver.analyser.Error(stmt.Keyword, "can not use 'return' outside function")
 		return nil
@@ -93,7 +165,13 @@ func (resolver *Resolver) VisitReturnStmt(stmt Return) any {
 	}
 
 	if resolver.currentFunction == INITIALIZER {
-		resolver.analyser.Error(stmt.Keyword, "can not use 'return' in initilzier function")
+		diagnostic := resolver.analyser.Error(stmt.Keyword, "can not use 'return' in initilzier function")
+		if value, ok := resolver.analyser.spans[stmt.Value]; ok {
+			resolver.analyser.fix(diagnostic, "Remove the return value", lsp.TextEdit{Range: lsp.Range{
+				Start: tokenRange(stmt.Keyword).End,
+				End:   value.Range().End,
+			}})
+		}
 		return nil
 	}
 
@@ -101,7 +179,7 @@ func (resolver *Resolver) VisitReturnStmt(stmt Return) any {
 	return nil
 }
 
-func (resolver *Resolver) VisitCallExpr(expr Call) any {
+func (resolver *Resolver) VisitCallExpr(expr *Call) any {
 	resolver.resolveExpr(expr.Callee)
 
 	for _, arg := range expr.Arguments {
@@ -126,9 +204,19 @@ func (resolver *Resolver) resolveLocal(expr Expr, token Token) {
 	for i := len(resolver.scopes) - 1; i >= 0; i-- {
 		if _, ok := resolver.scopes[i][token.Lexeme]; ok {
 			resolver.locals[expr] = len(resolver.scopes) - 1 - i
+			if symbol, ok := resolver.scopeTree[i].names[token.Lexeme]; ok {
+				resolver.bind(expr, symbol)
+			}
 			return
 		}
 	}
+
+	resolver.globalReferences = append(resolver.globalReferences, expr)
+}
+
+func (resolver *Resolver) bind(expr Expr, symbol *Symbol) {
+	resolver.bindings[expr] = symbol
+	symbol.References = append(symbol.References, expr)
 }
 
 func (resolver *Resolver) define(token Token) {
@@ -140,9 +228,10 @@ func (resolver *Resolver) define(token Token) {
 	resolver.scopes[lenScops-1][token.Lexeme] = true
 }
 
-func (resolver *Resolver) declare(token Token) {
+func (resolver *Resolver) declare(token Token, kind DeclarationKind, node Stmt) {
 	lenScops := len(resolver.scopes)
 	if lenScops == 0 {
+		resolver.global.declare(token, kind, node)
 		return
 	}
 
@@ -153,93 +242,126 @@ func (resolver *Resolver) declare(token Token) {
 	}
 
 	scope[token.Lexeme] = false
+	resolver.scopeTree[lenScops-1].declare(token, kind, node)
+}
+
+// newScope adds a scope to the tree under the innermost one.
+func (resolver *Resolver) newScope(kind ScopeKind, start Token, end Token) *Scope {
+	parent := resolver.global
+	if len(resolver.scopeTree) > 0 {
+		parent = resolver.scopeTree[len(resolver.scopeTree)-1]
+	}
+
+	return NewScope(kind, parent, start, end)
 }
 
-func (resolver *Resolver) beginScope() {
+func (resolver *Resolver) beginScope(scope *Scope) {
 	resolver.scopes = append(resolver.scopes, map[string]bool{})
+	resolver.scopeTree = append(resolver.scopeTree, scope)
 }
 
 func (resolver *Resolver) endScope() {
 	resolver.scopes = resolver.scopes[:len(resolver.scopes)-1]
+	resolver.scopeTree = resolver.scopeTree[:len(resolver.scopeTree)-1]
 }
 
-func (resolver *Resolver) VisitAssignExpr(expr Assign) any {
+func (resolver *Resolver) VisitAssignExpr(expr *Assign) any {
 	resolver.resolveExpr(expr.Value)
 	resolver.resolveLocal(expr, expr.Name)
 	return nil
 }
-func (resolver *Resolver) VisitBinaryExpr(expr Binary) any {
+func (resolver *Resolver) VisitBinaryExpr(expr *Binary) any {
 	resolver.resolveExpr(expr.Left)
 	resolver.resolveExpr(expr.Right)
 	return nil
 }
-func (resolver *Resolver) VisitGetExpr(expr Get) any {
+func (resolver *Resolver) VisitGetExpr(expr *Get) any {
 	resolver.resolveExpr(expr.Object)
 	return nil
 }
-func (resolver *Resolver) VisitGroupingExpr(expr Grouping) any {
+func (resolver *Resolver) VisitGroupingExpr(expr *Grouping) any {
 	resolver.resolveExpr(expr.Expression)
 	return nil
 }
-func (resolver *Resolver) VisitLiteralExpr(expr Literal) any {
+func (resolver *Resolver) VisitLiteralExpr(expr *Literal) any {
 	return nil
 }
-func (resolver *Resolver) VisitLogicalExpr(expr Logical) any {
+func (resolver *Resolver) VisitLogicalExpr(expr *Logical) any {
 	resolver.resolveExpr(expr.Left)
 	resolver.resolveExpr(expr.Right)
 	return nil
 }
-func (resolver *Resolver) VisitSetExpr(expr Set) any {
+
+func (resolver *Resolver) VisitMissingExpr(expr *Missing) any {
+	return nil
+}
+
+func (resolver *Resolver) VisitSetExpr(expr *Set) any {
 	resolver.resolveExpr(expr.Value)
 	resolver.resolveExpr(expr.Object)
 	return nil
 }
 
-func (resolver *Resolver) VisitUnaryExpr(expr Unary) any {
+func (resolver *Resolver) VisitUnaryExpr(expr *Unary) any {
 	resolver.resolveExpr(expr.Right)
 	return nil
 }
-func (resolver *Resolver) VisitVariableExpr(expr Variable) any {
+func (resolver *Resolver) VisitVariableExpr(expr *Variable) any {
 	lenScopes := len(resolver.scopes)
 	if lenScopes > 0 {
 		val, ok := resolver.scopes[lenScopes-1][expr.Name.Lexeme]
 		if ok && !val {
 			resolver.analyser.Error(expr.Name, "Can't read local variable in its own initializer.")
 		}
+	} else if expr.Name.Lexeme == resolver.initializing {
+		resolver.analyser.Error(expr.Name, "Can't read global variable in its own initializer.")
 	}
 
 	resolver.resolveLocal(expr, expr.Name)
 	return nil
 }
 
-func (resolver *Resolver) VisitBlockStmt(stmt Block) any {
-	resolver.Resolve(stmt.Statements)
+func (resolver *Resolver) VisitBlockStmt(stmt *Block) any {
+	if stmt.Sequence {
+		resolver.resolveStatements(stmt.Statements)
+		return nil
+	}
+
+	resolver.beginScope(resolver.newScope(BLOCK_SCOPE, stmt.Start, stmt.End))
+	resolver.resolveStatements(stmt.Statements)
+	resolver.endScope()
 	return nil
 }
-func (resolver *Resolver) VisitClassStmt(stmt Class) any {
+func (resolver *Resolver) VisitClassStmt(stmt *Class) any {
 	enclosingClass := resolver.currrntClass
 	resolver.currrntClass = CLASS_RESOLVER
 
-	resolver.declare(stmt.Name)
+	resolver.declare(stmt.Name, CLASS_DECLARATION, stmt)
 	resolver.define(stmt.Name)
 
-	var zeroSuperClass Variable
-	if stmt.Superclass != zeroSuperClass && stmt.Name.Lexeme == stmt.Superclass.Name.Lexeme {
+	if stmt.Superclass != nil && stmt.Name.Lexeme == stmt.Superclass.Name.Lexeme {
 		resolver.analyser.Error(stmt.Superclass.Name,
 			"A class can't inherit from itself.")
 	}
 
-	if stmt.Superclass != zeroSuperClass {
+	if stmt.Superclass != nil {
 		resolver.currrntClass = SUBCLASS
 		resolver.resolveExpr(stmt.Superclass)
 	}
 
-	if stmt.Superclass != zeroSuperClass {
-		resolver.beginScope()
+	// The scopes holding "super" and "this" are one class scope in the tree,
+	// where the methods are declared.
+	class := resolver.newScope(CLASS_SCOPE, stmt.Name, stmt.End)
+	for _, method := range stmt.Methods {
+		class.declare(method.Name, METHOD_DECLARATION, method)
+	}
+
+	if stmt.Superclass != nil {
+		resolver.beginScope(class)
 		resolver.scopes[len(resolver.scopes)-1]["super"] = true
 	}
 
-	resolver.beginScope()
+	resolver.beginScope(class)
 	resolver.scopes[len(resolver.scopes)-1]["this"] = true
 
 	for _, method := range stmt.Methods {
@@ -252,25 +374,25 @@ func (resolver *Resolver) VisitClassStmt(stmt Class) any {
 	}
 
 	resolver.endScope()
-	if stmt.Superclass != zeroSuperClass {
+	if stmt.Superclass != nil {
 		resolver.endScope()
 	}
 
 	resolver.currrntClass = enclosingClass
 	return nil
 }
-func (resolver *Resolver) VisitExpressionStmt(stmt Expression) any {
+func (resolver *Resolver) VisitExpressionStmt(stmt *Expression) any {
 	resolver.resolveExpr(stmt.Expression)
 	return nil
 }
-func (resolver *Resolver) VisitFunctionStmt(stmt Function) any {
-	resolver.declare(stmt.Name)
+func (resolver *Resolver) VisitFunctionStmt(stmt *Function) any {
+	resolver.declare(stmt.Name, FUNCTION_DECLARATION, stmt)
 	resolver.define(stmt.Name)
 
 	resolver.resolveFunction(stmt, FUNCTION)
 	return nil
 }
-func (resolver *Resolver) VisitIfStmt(stmt If) any {
+func (resolver *Resolver) VisitIfStmt(stmt *If) any {
 	resolver.resolveExpr(stmt.Condition)
 	resolver.resolveStmt(stmt.ThenBranch)
 	if stmt.ElseBranch != nil {
@@ -278,19 +400,24 @@ func (resolver *Resolver) VisitIfStmt(stmt If) any {
 	}
 	return nil
 }
-func (resolver *Resolver) VisitPrintStmt(stmt Print) any {
+func (resolver *Resolver) VisitPrintStmt(stmt *Print) any {
 	resolver.resolveExpr(stmt.Expression)
 	return nil
 }
-func (resolver *Resolver) VisitVarStmt(stmt Var) any {
-	resolver.declare(stmt.Name)
+func (resolver *Resolver) VisitVarStmt(stmt *Var) any {
+	// A global declared again reads the one before it in its initializer.
+	if _, declared := resolver.global.Lookup(stmt.Name.Lexeme); len(resolver.scopes) == 0 && !declared {
+		resolver.initializing = stmt.Name.Lexeme
+	}
+	resolver.declare(stmt.Name, VARIABLE_DECLARATION, stmt)
 	if stmt.Initializer != nil {
 		resolver.resolveExpr(stmt.Initializer)
 	}
+	resolver.initializing = ""
 	resolver.define(stmt.Name)
 	return nil
 }
-func (resolver *Resolver) VisitWhileStmt(stmt While) any {
+func (resolver *Resolver) VisitWhileStmt(stmt *While) any {
 	resolver.resolveExpr(stmt.Condition)
 	resolver.resolveStmt(stmt.Body)
 	return nil
@@ -0,0 +1,300 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+func resolve(t *testing.T, source string) ([]Stmt, *Resolver, *Analyser) {
+	t.Helper()
+
+	analyser := NewAnaylser()
+	scanner := NewScanner([]byte(source), analyser)
+	parser := NewParser(scanner.Scan(), analyser)
+	statements := parser.Parse()
+	resolver := NewResolver(analyser)
+	resolver.Resolve(statements)
+
+	return statements, resolver, analyser
+}
+
+// formatBindings lists every name reference of a program in source order
+// with the position of the declaration it resolved to.
+func formatBindings(statements []Stmt, resolver *Resolver) string {
+	var builder strings.Builder
+	WalkStatements(statements, func(node any) bool {
+		expr, ok := node.(Expr)
+		if !ok {
+			return true
+		}
+		name, ok := referenceName(expr)
+		if !ok {
+			return true
+		}
+
+		fmt.Fprintf(&builder, "%s %d:%d -> ", name.Lexeme, name.StartLine, name.StartChar)
+		if declaration, ok := resolver.Declaration(expr); ok {
+			fmt.Fprintf(&builder, "%d:%d\n", declaration.StartLine, declaration.StartChar)
+		} else {
+			builder.WriteString("unresolved\n")
+		}
+		return true
+	})
+
+	return builder.String()
+}
+
+func TestResolverBindsEachReference(t *testing.T) {
+	source := `var a = 1;
+fun f(a) {
+  print a;
+  a = 2;
+}
+print a;
+a = later;
+var later;
+`
+	statements, resolver, _ := resolve(t, source)
+
+	want := `a 2:8 -> 1:6
+a 3:2 -> 1:6
+a 5:6 -> 0:4
+a 6:0 -> 0:4
+later 6:4 -> 7:4
+`
+	if got := formatBindings(statements, resolver); got != want {
+		t.Errorf("bindings:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestResolverScopes(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		bindings    string
+		diagnostics string
+	}{
+		{
+			name: "block shadows global",
+			source: `var a = "outer";
+{
+  var a = "inner";
+  print a;
+}
+print a;
+`,
+			bindings: `a 3:8 -> 2:6
+a 5:6 -> 0:4
+`,
+		},
+		{
+			name: "sibling blocks",
+			source: `{
+  var a = 1;
+  print a;
+}
+{
+  var a = 2;
+  print a;
+}
+`,
+			bindings: `a 2:8 -> 1:6
+a 6:8 -> 5:6
+`,
+		},
+		{
+			name: "closure captures the scope it was declared in",
+			source: `var a = "global";
+{
+  fun showA() {
+    print a;
+  }
+
+  showA();
+  var a = "block";
+  showA();
+}
+`,
+			bindings: `a 3:10 -> 0:4
+showA 6:2 -> 2:6
+showA 8:2 -> 2:6
+`,
+		},
+		{
+			name: "closure over a local",
+			source: `fun makeCounter() {
+  var i = 0;
+  fun count() {
+    i = i + 1;
+    print i;
+  }
+
+  return count;
+}
+`,
+			bindings: `i 3:4 -> 1:6
+i 3:8 -> 1:6
+i 4:10 -> 1:6
+count 7:9 -> 2:6
+`,
+		},
+		{
+			name: "loop body shadows the for initializer",
+			source: `for (var i = 0; i < 3; i = i + 1) {
+  var i = "shadow";
+  print i;
+}
+print i;
+`,
+			bindings: `i 0:16 -> 0:9
+i 2:8 -> 1:6
+i 0:23 -> 0:9
+i 0:27 -> 0:9
+i 4:6 -> unresolved
+`,
+		},
+		{
+			name: "parameter shadowed in the body's block",
+			source: `fun f(a) {
+  {
+    var a = a;
+  }
+}
+`,
+			bindings: `a 2:12 -> 2:8
+`,
+			diagnostics: `2:12-13: Can't read local variable in its own initializer.
+`,
+		},
+		{
+			name: "duplicate local",
+			source: `{
+  var a = 1;
+  var a = 2;
+}
+var b;
+var b;
+`,
+			diagnostics: `2:6-7: a variable with this name has already been declared
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+
+			if got := formatBindings(statements, resolver); got != test.bindings {
+				t.Errorf("bindings:\n%s\nwant:\n%s", got, test.bindings)
+			}
+			if got := formatDiagnostics(analyser); got != test.diagnostics {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.diagnostics)
+			}
+		})
+	}
+}
+
+var scopeKindNames = map[ScopeKind]string{
+	GLOBAL_SCOPE:   "global",
+	FUNCTION_SCOPE: "function",
+	METHOD_SCOPE:   "method",
+	CLASS_SCOPE:    "class",
+	BLOCK_SCOPE:    "block",
+}
+
+var declarationKindNames = map[DeclarationKind]string{
+	VARIABLE_DECLARATION:  "var",
+	PARAMETER_DECLARATION: "parameter",
+	FUNCTION_DECLARATION:  "fun",
+	CLASS_DECLARATION:     "class",
+	METHOD_DECLARATION:    "method",
+}
+
+func formatScope(builder *strings.Builder, scope *Scope, indent string) {
+	fmt.Fprintf(builder, "%s%s %d:%d-%d:%d\n", indent, scopeKindNames[scope.Kind],
+		scope.Range.Start.Line, scope.Range.Start.Character, scope.Range.End.Line, scope.Range.End.Character)
+	for _, symbol := range scope.Symbols {
+		references := []string{}
+		for _, reference := range symbol.References {
+			name, _ := referenceName(reference)
+			references = append(references, fmt.Sprintf("%d:%d", name.StartLine, name.StartChar))
+		}
+		fmt.Fprintf(builder, "%s  %s %s [%s]\n", indent, declarationKindNames[symbol.Kind], symbol.Name,
+			strings.Join(references, " "))
+	}
+	for _, child := range scope.Children {
+		formatScope(builder, child, indent+"  ")
+	}
+}
+
+func TestScopeTree(t *testing.T) {
+	source := `var total = 0;
+class Counter {
+  add(n) {
+    total = total + n;
+  }
+}
+fun run(times) {
+  for (var i = 0; i < times; i = i + 1) {
+    Counter().add(i);
+  }
+}
+`
+	_, resolver, _ := resolve(t, source)
+
+	var builder strings.Builder
+	formatScope(&builder, resolver.Scopes(), "")
+
+	want := `global 0:0-0:0
+  var total [3:12 3:4]
+  class Counter [8:4]
+  fun run []
+  class 1:6-5:1
+    method add []
+    method 2:2-4:3
+      parameter n [3:20]
+  function 6:4-10:1
+    parameter times [7:22]
+    block 7:2-9:3
+      var i [7:18 8:18 7:33 7:29]
+      block 7:40-9:3
+`
+	if got := builder.String(); got != want {
+		t.Errorf("scope tree:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestScopeVisible(t *testing.T) {
+	source := `var a = 1;
+fun f(b) {
+  var c = 2;
+  {
+    var a = 3;
+    print a + b + c;
+  }
+}
+`
+	_, resolver, _ := resolve(t, source)
+
+	tests := []struct {
+		position lsp.Position
+		want     string
+	}{
+		{lsp.Position{Line: 0, Character: 0}, "a f"},
+		{lsp.Position{Line: 2, Character: 2}, "b a f"},
+		{lsp.Position{Line: 3, Character: 4}, "b c a f"},
+		{lsp.Position{Line: 5, Character: 4}, "a b c f"},
+	}
+	for _, test := range tests {
+		names := []string{}
+		for _, symbol := range resolver.Scopes().Visible(test.position) {
+			names = append(names, symbol.Name)
+		}
+		if got := strings.Join(names, " "); got != test.want {
+			t.Errorf("visible at %+v = %q, want %q", test.position, got, test.want)
+		}
+	}
+}
@@ -27,26 +27,9 @@ func NewScanner(source []byte, analyser *Analyser) Scanner {
 		current:   0,
 		startChar: 0,
 		endChar:   0,
-		line:      1,
+		line:      0,
 		length:    len(source),
-		keyWords: map[string]TokenType{
-			"and":    AND,
-			"class":  CLASS,
-			"else":   ELSE,
-			"false":  FALSE,
-			"for":    FOR,
-			"fun":    FUN,
-			"if":     IF,
-			"nil":    NIL,
-			"or":     OR,
-			"print":  PRINT,
-			"return": RETURN,
-			"super":  SUPER,
-			"this":   THIS,
-			"true":   TRUE,
-			"var":    VAR,
-			"while":  WHILE,
-		},
+		keyWords:  keywords,
 	}
 }
 
@@ -57,6 +40,8 @@ func (scanner *Scanner) Scan() []Token {
 		scanner.scanToken()
 	}
 
+	scanner.start = scanner.current
+	scanner.startChar = scanner.endChar
 	scanner.addToken(EOF, nil)
 	return scanner.tokens
 }
@@ -102,12 +87,9 @@ func (scanner *Scanner) scanToken() {
 	case '/':
 		{
 			if scanner.match('/') {
-				for !scanner.match('\n') {
+				for !scanner.isAtEnd() && scanner.peek() != '\n' {
 					scanner.advance()
 				}
-				scanner.line++
-				scanner.startChar = 0
-				scanner.endChar = 0
 				break
 			}
 			scanner.addToken(SLASH, nil)
@@ -202,7 +184,7 @@ func (scanner *Scanner) scanToken() {
 				StartChar: scanner.startChar,
 				EndChar:   scanner.endChar,
 				Lexeme:    "@",
-			}, fmt.Sprintf("Unexpected token %s", c))
+			}, fmt.Sprintf("Unexpected token %c", c))
 		}
 	}
 
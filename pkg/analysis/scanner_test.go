@@ -0,0 +1,28 @@
+package analysis
+
+import "testing"
+
+func TestScannerLinesStartAtZero(t *testing.T) {
+	source := `var a;
+print a;
+`
+
+	analyser := NewAnaylser()
+	scanner := NewScanner([]byte(source), analyser)
+	tokens := scanner.Scan()
+
+	want := map[string]int{"var": 0, "print": 1}
+	for _, token := range tokens {
+		line, ok := want[token.Lexeme]
+		if !ok {
+			continue
+		}
+		delete(want, token.Lexeme)
+		if token.StartLine != line {
+			t.Errorf("%s is on line %d, want %d", token.Lexeme, token.StartLine, line)
+		}
+	}
+	for lexeme := range want {
+		t.Errorf("%s was not scanned", lexeme)
+	}
+}
@@ -0,0 +1,142 @@
+package analysis
+
+import "github.com/neet-007/lox_lsp_first/internal/lsp"
+
+type ScopeKind int
+
+const (
+	GLOBAL_SCOPE ScopeKind = iota
+	FUNCTION_SCOPE
+	METHOD_SCOPE
+	CLASS_SCOPE
+	BLOCK_SCOPE
+)
+
+// Scope is a region of the program and the names declared directly in it.
+// The global scope covers the whole document and has a zero Range.
+type Scope struct {
+	Kind     ScopeKind
+	Range    lsp.Range
+	Parent   *Scope
+	Children []*Scope
+	Symbols  []*Symbol
+	names    map[string]*Symbol
+}
+
+// Symbol is one declaration and every expression the resolver bound to it.
+// Methods are symbols of their class scope, but are not variables that a
+// name in the class can refer to. Node is the Var, Function or Class that
+// declares it; a parameter's Node is its function.
+type Symbol struct {
+	Name       string
+	Token      Token
+	Kind       DeclarationKind
+	Node       Stmt
+	Scope      *Scope
+	References []Expr
+}
+
+func NewScope(kind ScopeKind, parent *Scope, start Token, end Token) *Scope {
+	scope := &Scope{
+		Kind:     kind,
+		Parent:   parent,
+		Children: []*Scope{},
+		Symbols:  []*Symbol{},
+		names:    map[string]*Symbol{},
+	}
+
+	if kind != GLOBAL_SCOPE {
+		scope.Range = lsp.Range{
+			Start: lsp.Position{Line: start.StartLine, Character: start.StartChar},
+			End:   lsp.Position{Line: end.StartLine, Character: end.EndChar},
+		}
+	}
+
+	if parent != nil {
+		parent.Children = append(parent.Children, scope)
+	}
+
+	return scope
+}
+
+// declare adds a symbol for token. A name declared twice in one scope keeps
+// its first symbol for lookups; the resolver reports the second.
+func (scope *Scope) declare(token Token, kind DeclarationKind, node Stmt) *Symbol {
+	symbol := &Symbol{
+		Name:       token.Lexeme,
+		Token:      token,
+		Kind:       kind,
+		Node:       node,
+		Scope:      scope,
+		References: []Expr{},
+	}
+	scope.Symbols = append(scope.Symbols, symbol)
+
+	if _, ok := scope.names[token.Lexeme]; !ok && kind != METHOD_DECLARATION {
+		scope.names[token.Lexeme] = symbol
+	}
+
+	return symbol
+}
+
+func (scope *Scope) Contains(position lsp.Position) bool {
+	if scope.Kind == GLOBAL_SCOPE {
+		return true
+	}
+
+	return !positionBefore(position, scope.Range.Start) && !positionBefore(scope.Range.End, position)
+}
+
+// ScopeAt returns the innermost scope under scope that contains position.
+func (scope *Scope) ScopeAt(position lsp.Position) *Scope {
+	for _, child := range scope.Children {
+		if child.Contains(position) {
+			return child.ScopeAt(position)
+		}
+	}
+
+	return scope
+}
+
+// Lookup finds the variable that name refers to in scope, looking through
+// the enclosing scopes.
+func (scope *Scope) Lookup(name string) (*Symbol, bool) {
+	for current := scope; current != nil; current = current.Parent {
+		if symbol, ok := current.names[name]; ok {
+			return symbol, true
+		}
+	}
+
+	return nil, false
+}
+
+// Visible returns the variables a name written at position can refer to,
+// innermost first and without the ones shadowed there. Locals count from
+// their declaration on; globals are visible everywhere, since a function
+// may use one that is declared after it.
+func (scope *Scope) Visible(position lsp.Position) []*Symbol {
+	symbols := []*Symbol{}
+	seen := map[string]bool{}
+
+	for current := scope.ScopeAt(position); current != nil; current = current.Parent {
+		for _, symbol := range current.Symbols {
+			if symbol.Kind == METHOD_DECLARATION || seen[symbol.Name] {
+				continue
+			}
+
+			declared := lsp.Position{Line: symbol.Token.StartLine, Character: symbol.Token.StartChar}
+			if current.Kind != GLOBAL_SCOPE && positionBefore(position, declared) {
+				continue
+			}
+
+			seen[symbol.Name] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	return symbols
+}
+
+func positionBefore(a lsp.Position, b lsp.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
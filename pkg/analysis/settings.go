@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Settings are the options a client sets with initializationOptions or
+// workspace/didChangeConfiguration. Options a client leaves out keep their
+// current value.
+type Settings struct {
+	Diagnostics DiagnosticSettings `json:"diagnostics"`
+	InlayHints  InlayHintSettings  `json:"inlayHints"`
+}
+
+type DiagnosticSettings struct {
+	// UnusedParameters warns about parameters that are never read.
+	UnusedParameters bool `json:"unusedParameters"`
+}
+
+type InlayHintSettings struct {
+	// ParameterNames names the parameter each argument of a call is for.
+	ParameterNames bool `json:"parameterNames"`
+	// VariableTypes shows the type inferred for each declared variable.
+	VariableTypes bool `json:"variableTypes"`
+	// ImplicitReturns shows where a function returns nil without saying so.
+	ImplicitReturns bool `json:"implicitReturns"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Diagnostics: DiagnosticSettings{
+			UnusedParameters: true,
+		},
+		InlayHints: InlayHintSettings{
+			ParameterNames: true,
+		},
+	}
+}
+
+// Configure applies the settings a client sent, which may be missing.
+func (analyser *Analyser) Configure(settings json.RawMessage) error {
+	if len(settings) == 0 || string(settings) == "null" {
+		return nil
+	}
+
+	return json.Unmarshal(settings, &analyser.settings)
+}
+
+// Documents returns the open documents ordered by uri, so they can be
+// analysed again when the settings change.
+func (analyser *Analyser) Documents() []*Document {
+	documents := []*Document{}
+	for _, document := range analyser.documents {
+		if !document.Indexed {
+			documents = append(documents, document)
+		}
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].Uri < documents[j].Uri
+	})
+
+	return documents
+}
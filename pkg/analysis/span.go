@@ -0,0 +1,18 @@
+package analysis
+
+import "github.com/neet-007/lox_lsp_first/internal/lsp"
+
+// Span is the first and last token of a statement or expression as it was
+// written. Nodes the parser made up, like the parts of a desugared for loop,
+// have none.
+type Span struct {
+	Start Token
+	End   Token
+}
+
+func (span Span) Range() lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: span.Start.StartLine, Character: span.Start.StartChar},
+		End:   lsp.Position{Line: span.End.StartLine, Character: span.End.EndChar},
+	}
+}
@@ -1,46 +1,53 @@
 package analysis
 
 type VisitStmt interface {
-	VisitBlockStmt(stmt Block) any
-	VisitClassStmt(stmt Class) any
-	VisitExpressionStmt(stmt Expression) any
-	VisitFunctionStmt(stmt Function) any
-	VisitIfStmt(stmt If) any
-	VisitPrintStmt(stmt Print) any
-	VisitReturnStmt(stmt Return) any
-	VisitVarStmt(stmt Var) any
-	VisitWhileStmt(stmt While) any
+	VisitBlockStmt(stmt *Block) any
+	VisitClassStmt(stmt *Class) any
+	VisitExpressionStmt(stmt *Expression) any
+	VisitFunctionStmt(stmt *Function) any
+	VisitIfStmt(stmt *If) any
+	VisitPrintStmt(stmt *Print) any
+	VisitReturnStmt(stmt *Return) any
+	VisitVarStmt(stmt *Var) any
+	VisitWhileStmt(stmt *While) any
 }
 
 type Stmt interface {
 	Accept(visitor VisitStmt) any
 }
 
-// Block
+// Block. Start and End are its braces, or the first and last tokens of the
+// loop a for statement is desugared into. Sequence marks the block such a
+// loop runs its body and then its increment in, which has no scope of its
+// own.
 type Block struct {
 	Statements []Stmt
+	Start      Token
+	End        Token
+	Sequence   bool
 }
 
-func NewBlock(statements []Stmt) Block {
-	return Block{statements}
+func NewBlock(statements []Stmt, start Token, end Token) *Block {
+	return &Block{Statements: statements, Start: start, End: end}
 }
 
-func (b Block) Accept(visitor VisitStmt) any {
+func (b *Block) Accept(visitor VisitStmt) any {
 	return visitor.VisitBlockStmt(b)
 }
 
-// Class
+// Class. End is the '}' that closes its body.
 type Class struct {
 	Name       Token
-	Superclass Variable
-	Methods    []Function
+	Superclass *Variable
+	Methods    []*Function
+	End        Token
 }
 
-func NewClass(name Token, superclass Variable, methods []Function) Class {
-	return Class{name, superclass, methods}
+func NewClass(name Token, superclass *Variable, methods []*Function, end Token) *Class {
+	return &Class{name, superclass, methods, end}
 }
 
-func (c Class) Accept(visitor VisitStmt) any {
+func (c *Class) Accept(visitor VisitStmt) any {
 	return visitor.VisitClassStmt(c)
 }
 
@@ -49,26 +56,27 @@ type Expression struct {
 	Expression Expr
 }
 
-func NewExpression(expression Expr) Expression {
-	return Expression{expression}
+func NewExpression(expression Expr) *Expression {
+	return &Expression{expression}
 }
 
-func (e Expression) Accept(visitor VisitStmt) any {
+func (e *Expression) Accept(visitor VisitStmt) any {
 	return visitor.VisitExpressionStmt(e)
 }
 
-// Function
+// Function. End is the '}' that closes its body.
 type Function struct {
 	Name   Token
 	Params []Token
 	Body   []Stmt
+	End    Token
 }
 
-func NewFunction(name Token, params []Token, body []Stmt) Function {
-	return Function{name, params, body}
+func NewFunction(name Token, params []Token, body []Stmt, end Token) *Function {
+	return &Function{name, params, body, end}
 }
 
-func (f Function) Accept(visitor VisitStmt) any {
+func (f *Function) Accept(visitor VisitStmt) any {
 	return visitor.VisitFunctionStmt(f)
 }
 
@@ -79,11 +87,11 @@ type If struct {
 	ElseBranch Stmt
 }
 
-func NewIf(condition Expr, thenBranch Stmt, elseBranch Stmt) If {
-	return If{condition, thenBranch, elseBranch}
+func NewIf(condition Expr, thenBranch Stmt, elseBranch Stmt) *If {
+	return &If{condition, thenBranch, elseBranch}
 }
 
-func (i If) Accept(visitor VisitStmt) any {
+func (i *If) Accept(visitor VisitStmt) any {
 	return visitor.VisitIfStmt(i)
 }
 
@@ -92,11 +100,11 @@ type Print struct {
 	Expression Expr
 }
 
-func NewPrint(expression Expr) Print {
-	return Print{expression}
+func NewPrint(expression Expr) *Print {
+	return &Print{expression}
 }
 
-func (p Print) Accept(visitor VisitStmt) any {
+func (p *Print) Accept(visitor VisitStmt) any {
 	return visitor.VisitPrintStmt(p)
 }
 
@@ -106,11 +114,11 @@ type Return struct {
 	Value   Expr
 }
 
-func NewReturn(keyword Token, value Expr) Return {
-	return Return{keyword, value}
+func NewReturn(keyword Token, value Expr) *Return {
+	return &Return{keyword, value}
 }
 
-func (r Return) Accept(visitor VisitStmt) any {
+func (r *Return) Accept(visitor VisitStmt) any {
 	return visitor.VisitReturnStmt(r)
 }
 
@@ -120,11 +128,11 @@ type Var struct {
 	Initializer Expr
 }
 
-func NewVar(name Token, initializer Expr) Var {
-	return Var{name, initializer}
+func NewVar(name Token, initializer Expr) *Var {
+	return &Var{name, initializer}
 }
 
-func (v Var) Accept(visitor VisitStmt) any {
+func (v *Var) Accept(visitor VisitStmt) any {
 	return visitor.VisitVarStmt(v)
 }
 
@@ -134,10 +142,10 @@ type While struct {
 	Body      Stmt
 }
 
-func NewWhile(condition Expr, body Stmt) While {
-	return While{condition, body}
+func NewWhile(condition Expr, body Stmt) *While {
+	return &While{condition, body}
 }
 
-func (w While) Accept(visitor VisitStmt) any {
+func (w *While) Accept(visitor VisitStmt) any {
 	return visitor.VisitWhileStmt(w)
 }
@@ -0,0 +1,161 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// PrepareTypeHierarchy returns the class named at position.
+func (analyser *Analyser) PrepareTypeHierarchy(id int, uri string, position lsp.Position) lsp.TypeHierarchyResponse {
+	response := newTypeHierarchyResponse(id)
+
+	document, ok := analyser.documents[uri]
+	if !ok {
+		return response
+	}
+	token, ok := document.tokenAt(position)
+	if !ok {
+		return response
+	}
+	declaration, ok := document.declarationOf(token)
+	if !ok || declaration.Kind != CLASS_DECLARATION {
+		return response
+	}
+
+	if node, ok := document.callable(declaration.Token); ok {
+		if class, ok := node.(*Class); ok {
+			response.Result = append(response.Result, document.typeItem(class))
+		}
+	}
+
+	return response
+}
+
+// Supertypes returns the class that the class of item inherits from, which
+// may be declared in another file.
+func (analyser *Analyser) Supertypes(id int, item lsp.TypeHierarchyItem) lsp.TypeHierarchyResponse {
+	response := newTypeHierarchyResponse(id)
+
+	document, class, ok := analyser.itemClass(item)
+	if !ok {
+		return response
+	}
+	if superclass, declaring := analyser.superclassIn(document, class); superclass != nil {
+		response.Result = append(response.Result, declaring.typeItem(superclass))
+	}
+
+	return response
+}
+
+// Subtypes returns the classes of every open or indexed file that inherit
+// from the class of item directly.
+func (analyser *Analyser) Subtypes(id int, item lsp.TypeHierarchyItem) lsp.TypeHierarchyResponse {
+	response := newTypeHierarchyResponse(id)
+
+	_, class, ok := analyser.itemClass(item)
+	if !ok {
+		return response
+	}
+	for _, subclass := range analyser.subclasses()[class] {
+		response.Result = append(response.Result, subclass.document.typeItem(subclass.class))
+	}
+
+	return response
+}
+
+// declaredClass is a class with the document that declares it.
+type declaredClass struct {
+	document *Document
+	class    *Class
+}
+
+// superclassIn returns the class that class, declared in document, inherits
+// from and the document that declares it. A superclass the document does not
+// declare is looked up in the other documents, as a global of theirs.
+func (analyser *Analyser) superclassIn(document *Document, class *Class) (*Class, *Document) {
+	if superclass := superclassOf(class, document.resolver); superclass != nil {
+		return superclass, document
+	}
+	if class.Superclass == nil {
+		return nil, nil
+	}
+	if _, ok := document.resolver.SymbolOf(class.Superclass); ok {
+		return nil, nil
+	}
+
+	node, declaring, ok := analyser.globalOf(class.Superclass.Name.Lexeme, document)
+	if !ok {
+		return nil, nil
+	}
+	superclass, ok := node.(*Class)
+	if !ok {
+		return nil, nil
+	}
+
+	return superclass, declaring
+}
+
+// subclasses indexes the classes of every open or indexed document by the
+// class they inherit from directly, in the order of their documents.
+func (analyser *Analyser) subclasses() map[*Class][]declaredClass {
+	index := map[*Class][]declaredClass{}
+	for _, document := range analyser.workspace() {
+		for _, class := range document.fields.classes {
+			if superclass, _ := analyser.superclassIn(document, class); superclass != nil {
+				index[superclass] = append(index[superclass], declaredClass{document, class})
+			}
+		}
+	}
+
+	return index
+}
+
+func newTypeHierarchyResponse(id int) lsp.TypeHierarchyResponse {
+	return lsp.TypeHierarchyResponse{
+		Response: lsp.Response{
+			RPC: "2.0",
+			Id:  &id,
+		},
+		Result: []lsp.TypeHierarchyItem{},
+	}
+}
+
+func (analyser *Analyser) itemClass(item lsp.TypeHierarchyItem) (*Document, *Class, bool) {
+	document, ok := analyser.documents[item.URI]
+	if !ok {
+		return nil, nil, false
+	}
+	node, ok := document.itemNode(item.Kind, item.SelectionRange)
+	if !ok {
+		return nil, nil, false
+	}
+
+	class, ok := node.(*Class)
+	return document, class, ok
+}
+
+// typeItem makes the item of a class, whose detail lists the methods the
+// class declares.
+func (document *Document) typeItem(class *Class) lsp.TypeHierarchyItem {
+	symbol := document.symbol(class.Name, lsp.SymbolKindClass, Span{Start: class.Name, End: class.End}.Range())
+
+	methods := []string{}
+	for _, method := range class.Methods {
+		methods = append(methods, functionSignature(method.Name.Lexeme, method))
+	}
+	detail := symbol.Detail
+	if len(methods) > 0 {
+		detail += fmt.Sprintf("; methods %s", strings.Join(methods, ", "))
+	}
+
+	return lsp.TypeHierarchyItem{
+		Name:           symbol.Name,
+		Kind:           symbol.Kind,
+		Detail:         detail,
+		URI:            document.Uri,
+		Range:          symbol.Range,
+		SelectionRange: symbol.SelectionRange,
+	}
+}
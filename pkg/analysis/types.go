@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+type TypeKind int
+
+const (
+	NUMBER_TYPE TypeKind = 1 << iota
+	STRING_TYPE
+	BOOL_TYPE
+	NIL_TYPE
+	FUNCTION_TYPE
+	CLASS_TYPE
+	INSTANCE_TYPE
+
+	ANY_TYPE = NUMBER_TYPE | STRING_TYPE | BOOL_TYPE | NIL_TYPE | FUNCTION_TYPE | CLASS_TYPE | INSTANCE_TYPE
+)
+
+// Type is the set of kinds of value an expression may have. Function is
+// the function a FUNCTION_TYPE value is, and Class the class of a
+// CLASS_TYPE or INSTANCE_TYPE value, when only one is possible.
+type Type struct {
+	Kinds    TypeKind
+	Function *Function
+	Class    *Class
+}
+
+var AnyType = Type{Kinds: ANY_TYPE}
+
+func NewType(kinds TypeKind) Type {
+	return Type{Kinds: kinds}
+}
+
+func NewFunctionType(function *Function) Type {
+	return Type{Kinds: FUNCTION_TYPE, Function: function}
+}
+
+func NewClassType(class *Class) Type {
+	return Type{Kinds: CLASS_TYPE, Class: class}
+}
+
+func NewInstanceType(class *Class) Type {
+	return Type{Kinds: INSTANCE_TYPE, Class: class}
+}
+
+// Union is the type of a value that has either type.
+func (t Type) Union(other Type) Type {
+	union := Type{Kinds: t.Kinds | other.Kinds}
+
+	if t.Kinds&FUNCTION_TYPE == 0 {
+		union.Function = other.Function
+	} else if other.Kinds&FUNCTION_TYPE == 0 || t.Function == other.Function {
+		union.Function = t.Function
+	}
+
+	const classKinds = CLASS_TYPE | INSTANCE_TYPE
+	if t.Kinds&classKinds == 0 {
+		union.Class = other.Class
+	} else if other.Kinds&classKinds == 0 || t.Class == other.Class {
+		union.Class = t.Class
+	}
+
+	return union
+}
+
+// May reports whether a value of type t can be of one of kinds.
+func (t Type) May(kinds TypeKind) bool {
+	return t.Kinds&kinds != 0
+}
+
+// Known reports whether anything is known about t.
+func (t Type) Known() bool {
+	return t.Kinds != 0 && t.Kinds != ANY_TYPE
+}
+
+func (t Type) String() string {
+	if t.Kinds == ANY_TYPE {
+		return "any"
+	}
+	if t.Kinds == 0 {
+		return "never"
+	}
+
+	parts := []string{}
+	for _, kind := range []TypeKind{NUMBER_TYPE, STRING_TYPE, BOOL_TYPE, NIL_TYPE, FUNCTION_TYPE, CLASS_TYPE, INSTANCE_TYPE} {
+		if t.Kinds&kind == 0 {
+			continue
+		}
+
+		switch kind {
+		case NUMBER_TYPE:
+			parts = append(parts, "number")
+		case STRING_TYPE:
+			parts = append(parts, "string")
+		case BOOL_TYPE:
+			parts = append(parts, "bool")
+		case NIL_TYPE:
+			parts = append(parts, "nil")
+		case FUNCTION_TYPE:
+			if t.Function != nil {
+				parts = append(parts, functionSignature("fun", t.Function))
+			} else {
+				parts = append(parts, "function")
+			}
+		case CLASS_TYPE:
+			if t.Class != nil {
+				parts = append(parts, fmt.Sprintf("class %s", t.Class.Name.Lexeme))
+			} else {
+				parts = append(parts, "class")
+			}
+		case INSTANCE_TYPE:
+			if t.Class != nil {
+				parts = append(parts, t.Class.Name.Lexeme)
+			} else {
+				parts = append(parts, "instance")
+			}
+		}
+	}
+
+	return strings.Join(parts, " | ")
+}
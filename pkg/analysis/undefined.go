@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// checkUndefined reports the names that do not refer to any declaration,
+// and the globals that top level code uses before declaring them. A
+// function body may use a global declared after it, since it runs later.
+// Superclasses are left to checkInheritance.
+func (analyser *Analyser) checkUndefined(statements []Stmt, resolver *Resolver) {
+	superclasses := map[Expr]bool{}
+	WalkStatements(statements, func(node any) bool {
+		var name Token
+		switch node := node.(type) {
+		case *Class:
+			if node.Superclass != nil {
+				superclasses[node.Superclass] = true
+			}
+			return true
+		case *Variable:
+			if superclasses[node] {
+				return true
+			}
+			name = node.Name
+		case *Assign:
+			name = node.Name
+		default:
+			return true
+		}
+
+		position := lsp.Position{Line: name.StartLine, Character: name.StartChar}
+		symbol, declared := resolver.SymbolOf(node.(Expr))
+		if declared && declaredBefore(symbol, position, resolver.Scopes()) {
+			return true
+		}
+
+		message := fmt.Sprintf("undefined variable '%s'", name.Lexeme)
+		suggestion, ok := suggestName(name.Lexeme, position, resolver.Scopes())
+		if ok {
+			message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		diagnostic := analyser.Error(name, message)
+		if ok {
+			analyser.renameFix(diagnostic, name, suggestion)
+		}
+		// A global used before its declaration needs moving instead.
+		if !declared && name.Lexeme != "" {
+			if edit, ok := analyser.declareBefore(statements, position, name.Lexeme); ok {
+				analyser.fix(diagnostic, fmt.Sprintf("Declare '%s'", name.Lexeme), edit)
+			}
+		}
+		return true
+	})
+}
+
+// renameFix offers to replace the misspelled name with suggestion.
+func (analyser *Analyser) renameFix(diagnostic *lsp.Diagnostic, name Token, suggestion string) {
+	analyser.fix(diagnostic, fmt.Sprintf("Change to '%s'", suggestion), lsp.TextEdit{Range: tokenRange(name), NewText: suggestion})
+}
+
+// declaredBefore reports whether symbol can be used at position: locals
+// are only bound once declared, and globals must be declared first unless
+// the use is in a function body.
+func declaredBefore(symbol *Symbol, position lsp.Position, global *Scope) bool {
+	if symbol.Scope.Kind != GLOBAL_SCOPE {
+		return true
+	}
+
+	declared := lsp.Position{Line: symbol.Token.StartLine, Character: symbol.Token.StartChar}
+	if positionBefore(declared, position) {
+		return true
+	}
+
+	for scope := global.ScopeAt(position); scope != nil; scope = scope.Parent {
+		if scope.Kind == FUNCTION_SCOPE || scope.Kind == METHOD_SCOPE {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suggestName finds the name usable at position that is closest to name,
+// if one is close enough to be a likely misspelling.
+func suggestName(name string, position lsp.Position, global *Scope) (string, bool) {
+	limit := max(len(name)/3, 1)
+	suggestion := ""
+	best := limit + 1
+
+	for _, symbol := range global.Visible(position) {
+		if symbol.Name == name || !declaredBefore(symbol, position, global) {
+			continue
+		}
+
+		distance := editDistance(name, symbol.Name)
+		if distance < best && distance < len(name) {
+			suggestion = symbol.Name
+			best = distance
+		}
+	}
+
+	return suggestion, suggestion != ""
+}
+
+// editDistance is the number of single character insertions, deletions,
+// substitutions and swaps of neighbouring characters that turn a into b.
+func editDistance(a string, b string) int {
+	before := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], before[j-2]+1)
+			}
+		}
+		before, previous, current = previous, current, before
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import "testing"
+
+func TestUndefined(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name: "misspelled local",
+			source: `fun f(count) {
+  var total = 0;
+  print totl + cuont;
+}
+`,
+			want: `2:8-12: undefined variable 'totl', did you mean 'total'?
+2:15-20: undefined variable 'cuont', did you mean 'count'?
+`,
+		},
+		{
+			name: "no close name",
+			source: `var a = 1;
+print b;
+zebra = 2;
+`,
+			want: `1:6-7: undefined variable 'b'
+2:0-5: undefined variable 'zebra'
+`,
+		},
+		{
+			name: "forward references",
+			source: `print later;
+fun f() {
+  print later;
+}
+{
+  later = 1;
+}
+var later;
+`,
+			want: `0:6-11: undefined variable 'later'
+5:2-7: undefined variable 'later'
+`,
+		},
+		{
+			name: "global read in its own initializer",
+			source: `var a = a;
+var b = 1;
+var b = b + 1;
+`,
+			want: `0:8-9: Can't read global variable in its own initializer.
+`,
+		},
+		{
+			name: "local used before its declaration",
+			source: `{
+  print value;
+  var value = 1;
+  print valeu;
+}
+`,
+			want: `1:8-13: undefined variable 'value'
+3:8-13: undefined variable 'valeu', did you mean 'value'?
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			analyser.checkUndefined(statements, resolver)
+
+			if got := formatDiagnostics(analyser); got != test.want {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"total", "totl", 1},
+		{"count", "cuont", 1},
+		{"ab", "ba", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
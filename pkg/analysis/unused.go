@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+)
+
+// checkUnused warns about local variables and parameters that are never
+// read, functions that are never used outside their own body and classes
+// that are never instantiated. Names starting with '_' are meant to be
+// unused and are left alone.
+func (analyser *Analyser) checkUnused(statements []Stmt, resolver *Resolver) {
+	inert := inertReferences(statements)
+
+	var check func(scope *Scope)
+	check = func(scope *Scope) {
+		declared := map[string]bool{}
+		for _, symbol := range scope.Symbols {
+			// Uses of a name declared twice are bound to the first
+			// declaration; the second is reported by the resolver.
+			if declared[symbol.Name] {
+				continue
+			}
+			declared[symbol.Name] = true
+
+			if strings.HasPrefix(symbol.Name, "_") {
+				continue
+			}
+
+			switch symbol.Kind {
+			case VARIABLE_DECLARATION:
+				if scope.Kind != GLOBAL_SCOPE && !isRead(symbol) {
+					analyser.removeVariableFix(analyser.Unnecessary(symbol.Token,
+						fmt.Sprintf("unused variable '%s'", symbol.Name)), symbol)
+				}
+			case PARAMETER_DECLARATION:
+				if analyser.settings.Diagnostics.UnusedParameters && !isRead(symbol) {
+					analyser.Unnecessary(symbol.Token, fmt.Sprintf("unused parameter '%s'", symbol.Name))
+				}
+			case FUNCTION_DECLARATION:
+				if !isCalledOutside(symbol, scope) {
+					analyser.Unnecessary(symbol.Token, fmt.Sprintf("unused function '%s'", symbol.Name))
+				}
+			case CLASS_DECLARATION:
+				if !isInstantiated(symbol, inert) {
+					analyser.Unnecessary(symbol.Token, fmt.Sprintf("class '%s' is never instantiated", symbol.Name))
+				}
+			}
+		}
+
+		for _, child := range scope.Children {
+			check(child)
+		}
+	}
+
+	check(resolver.Scopes())
+}
+
+// removeVariableFix offers to remove the declaration of a variable that is
+// never used. An initializer that does something is kept as a statement.
+func (analyser *Analyser) removeVariableFix(diagnostic *lsp.Diagnostic, symbol *Symbol) {
+	stmt, ok := symbol.Node.(*Var)
+	span, spanned := analyser.spans[stmt]
+	if !ok || !spanned || len(symbol.References) > 0 {
+		return
+	}
+
+	title := fmt.Sprintf("Remove unused variable '%s'", symbol.Name)
+	if stmt.Initializer == nil || sideEffectFree(stmt.Initializer) {
+		analyser.fix(diagnostic, title, lsp.TextEdit{Range: removal(analyser.source, span)})
+		return
+	}
+
+	initializer, ok := analyser.spans[stmt.Initializer]
+	if !ok {
+		return
+	}
+	analyser.fix(diagnostic, title, lsp.TextEdit{Range: lsp.Range{
+		Start: span.Range().Start,
+		End:   initializer.Range().Start,
+	}})
+}
+
+func isRead(symbol *Symbol) bool {
+	for _, reference := range symbol.References {
+		if _, ok := reference.(*Assign); !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isCalledOutside reports whether a function is read anywhere but in its
+// own body, where it can only call itself.
+func isCalledOutside(symbol *Symbol, scope *Scope) bool {
+	var body *Scope
+	for _, child := range scope.Children {
+		if child.Kind == FUNCTION_SCOPE && child.Range.Start.Line == symbol.Token.StartLine &&
+			child.Range.Start.Character == symbol.Token.StartChar {
+			body = child
+			break
+		}
+	}
+
+	for _, reference := range symbol.References {
+		if _, ok := reference.(*Assign); ok {
+			continue
+		}
+
+		name, _ := referenceName(reference)
+		position := lsp.Position{Line: name.StartLine, Character: name.StartChar}
+		if body == nil || !body.Contains(position) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isInstantiated reports whether a class may have instances: it is called,
+// inherited from, or its value goes somewhere it could be.
+func isInstantiated(symbol *Symbol, inert map[Expr]bool) bool {
+	for _, reference := range symbol.References {
+		if !inert[reference] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inertReferences collects the references whose value cannot end up being
+// called: assignments to them, and values that are only printed, compared,
+// used for a property or thrown away.
+func inertReferences(statements []Stmt) map[Expr]bool {
+	inert := map[Expr]bool{}
+	mark := func(expr Expr) {
+		if _, ok := referenceName(expr); ok {
+			inert[expr] = true
+		}
+	}
+
+	WalkStatements(statements, func(node any) bool {
+		switch node := node.(type) {
+		case *Assign:
+			inert[node] = true
+		case *Expression:
+			mark(node.Expression)
+		case *Print:
+			mark(node.Expression)
+		case *Get:
+			mark(node.Object)
+		case *Binary:
+			mark(node.Left)
+			mark(node.Right)
+		}
+		return true
+	})
+
+	return inert
+}
@@ -0,0 +1,75 @@
+package analysis
+
+import "testing"
+
+func TestUnused(t *testing.T) {
+	tests := []struct {
+		name             string
+		source           string
+		unusedParameters bool
+		want             string
+	}{
+		{
+			name: "locals and parameters",
+			source: `fun f(a, b, _c) {
+  var d = 1;
+  var _e = 2;
+  var g;
+  g = 3;
+  print a;
+}
+f(1, 2, 3);
+`,
+			unusedParameters: true,
+			want: `0:9-10: unused parameter 'b'
+1:6-7: unused variable 'd'
+3:6-7: unused variable 'g'
+`,
+		},
+		{
+			name: "parameters turned off",
+			source: `fun f(a) {}
+f(1);
+`,
+			want: "",
+		},
+		{
+			name: "function that only calls itself",
+			source: `fun loop(n) {
+  if (n > 0) loop(n - 1);
+}
+fun used() {}
+used();
+`,
+			unusedParameters: true,
+			want: `0:4-8: unused function 'loop'
+`,
+		},
+		{
+			name: "classes",
+			source: `class Never {}
+print Never;
+class Base {}
+class Derived < Base {}
+var instance = Derived();
+class Passed {}
+var factory = Passed;
+`,
+			unusedParameters: true,
+			want: `0:6-11: class 'Never' is never instantiated
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, resolver, analyser := resolve(t, test.source)
+			analyser.settings.Diagnostics.UnusedParameters = test.unusedParameters
+			analyser.checkUnused(statements, resolver)
+
+			if got := formatDiagnostics(analyser); got != test.want {
+				t.Errorf("diagnostics:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package analysis
+
+// Walk calls visit for node and then, if visit returns true, for each of its
+// children in source order. Nodes are the Stmt and Expr values of the tree;
+// nil children are skipped.
+func Walk(node any, visit func(node any) bool) {
+	switch node.(type) {
+	case Stmt, Expr:
+		if !visit(node) {
+			return
+		}
+	default:
+		return
+	}
+
+	walkExpr := func(expr Expr) {
+		if expr != nil {
+			Walk(expr, visit)
+		}
+	}
+	walkStmt := func(stmt Stmt) {
+		if stmt != nil {
+			Walk(stmt, visit)
+		}
+	}
+
+	switch node := node.(type) {
+	case *Assign:
+		walkExpr(node.Value)
+	case *Binary:
+		walkExpr(node.Left)
+		walkExpr(node.Right)
+	case *Call:
+		walkExpr(node.Callee)
+		for _, arg := range node.Arguments {
+			walkExpr(arg)
+		}
+	case *Get:
+		walkExpr(node.Object)
+	case *Grouping:
+		walkExpr(node.Expression)
+	case *Logical:
+		walkExpr(node.Left)
+		walkExpr(node.Right)
+	case *Set:
+		walkExpr(node.Object)
+		walkExpr(node.Value)
+	case *Unary:
+		walkExpr(node.Right)
+	case *Block:
+		for _, stmt := range node.Statements {
+			walkStmt(stmt)
+		}
+	case *Class:
+		if node.Superclass != nil {
+			walkExpr(node.Superclass)
+		}
+		for _, method := range node.Methods {
+			walkStmt(method)
+		}
+	case *Expression:
+		walkExpr(node.Expression)
+	case *Function:
+		for _, stmt := range node.Body {
+			walkStmt(stmt)
+		}
+	case *If:
+		walkExpr(node.Condition)
+		walkStmt(node.ThenBranch)
+		walkStmt(node.ElseBranch)
+	case *Print:
+		walkExpr(node.Expression)
+	case *Return:
+		walkExpr(node.Value)
+	case *Var:
+		walkExpr(node.Initializer)
+	case *While:
+		walkExpr(node.Condition)
+		walkStmt(node.Body)
+	}
+}
+
+// WalkStatements walks every statement of a program in order.
+func WalkStatements(statements []Stmt, visit func(node any) bool) {
+	for _, stmt := range statements {
+		if stmt != nil {
+			Walk(stmt, visit)
+		}
+	}
+}
+
+// referenceName returns the name token of an expression that refers to a
+// declaration by name.
+func referenceName(expr Expr) (Token, bool) {
+	switch expr := expr.(type) {
+	case *Variable:
+		return expr.Name, true
+	case *Assign:
+		return expr.Name, true
+	case *This:
+		return expr.Keyword, true
+	case *Super:
+		return expr.Keyword, true
+	}
+
+	return Token{}, false
+}
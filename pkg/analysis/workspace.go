@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Index analyses the Lox files under the workspace folders given by their
+// uris, so that requests can reach declarations in files that are not
+// open. Files that are open are left alone. It returns how many files it
+// read, none after SkipIndex.
+func (analyser *Analyser) Index(roots []string, logger *log.Logger) int {
+	indexed := 0
+	if analyser.skipIndex {
+		return indexed
+	}
+	for _, root := range roots {
+		rootURL, err := url.Parse(root)
+		if err != nil || rootURL.Scheme != "file" {
+			logger.Printf("index: can not index %s", root)
+			continue
+		}
+
+		filepath.WalkDir(filepath.FromSlash(rootURL.Path), func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				logger.Printf("index: %s", err)
+				return nil
+			}
+			if entry.IsDir() {
+				if path != filepath.FromSlash(rootURL.Path) && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) != ".lox" {
+				return nil
+			}
+
+			uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+			if _, ok := analyser.documents[uri]; ok {
+				return nil
+			}
+
+			source, err := os.ReadFile(path)
+			if err != nil {
+				logger.Printf("index: %s", err)
+				return nil
+			}
+			analyser.Analyse(source, uri, logger)
+			analyser.documents[uri].Indexed = true
+			indexed++
+			return nil
+		})
+	}
+
+	return indexed
+}
+
+// SkipIndex stops Index from reading the disk, for a server whose replies
+// must not depend on the files of the machine it runs on.
+func (analyser *Analyser) SkipIndex() {
+	analyser.skipIndex = true
+}
+
+// workspace returns every document, open or indexed, ordered by uri.
+func (analyser *Analyser) workspace() []*Document {
+	documents := []*Document{}
+	for _, document := range analyser.documents {
+		documents = append(documents, document)
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].Uri < documents[j].Uri
+	})
+
+	return documents
+}
+
+// globalOf finds the function or class declared as the global name by a
+// document other than document, and the document that declares it. Lox has
+// no imports, but the files of a workspace share one global environment, so
+// a name that a file uses without declaring it is the one another file
+// declares. The first document by uri wins.
+func (analyser *Analyser) globalOf(name string, document *Document) (any, *Document, bool) {
+	for _, other := range analyser.workspace() {
+		if other == document {
+			continue
+		}
+		symbol, ok := other.resolver.Scopes().Lookup(name)
+		if !ok {
+			continue
+		}
+		switch node := symbol.Node.(type) {
+		case *Function:
+			if symbol.Kind == FUNCTION_DECLARATION {
+				return node, other, true
+			}
+		case *Class:
+			if symbol.Kind == CLASS_DECLARATION {
+				return node, other, true
+			}
+		}
+	}
+
+	return nil, nil, false
+}
@@ -1,13 +1,27 @@
 package rpc
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strconv"
 )
 
+const (
+	// MaxContentLength is the largest content Split frames. A message whose
+	// header says it is longer is skipped before any of it is buffered.
+	MaxContentLength = 64 * 1024 * 1024
+	// maxHeaderLength is how far Split looks for the end of a header before
+	// it drops what it has read.
+	maxHeaderLength = 4 * 1024
+	// MaxMessageSize is the buffer a bufio.Scanner needs to frame every
+	// message Split accepts.
+	MaxMessageSize = maxHeaderLength + 4 + MaxContentLength
+)
+
 func EncodeMessage(msg any) string {
 	content, err := json.Marshal(msg)
 	if err != nil {
@@ -21,17 +35,44 @@ type BaseMessage struct {
 	Method string `json:"method"`
 }
 
+// contentLength reads the Content-Length field of a header block. Other
+// fields such as Content-Type are allowed and ignored.
+func contentLength(header []byte) (int, error) {
+	for _, field := range bytes.Split(header, []byte{'\r', '\n'}) {
+		name, value, found := bytes.Cut(field, []byte{':'})
+		if !found || !bytes.EqualFold(bytes.TrimSpace(name), []byte("Content-Length")) {
+			continue
+		}
+
+		length, err := strconv.Atoi(string(bytes.TrimSpace(value)))
+		if err != nil {
+			return 0, err
+		}
+		if length < 0 {
+			return 0, fmt.Errorf("negative content length %d", length)
+		}
+
+		return length, nil
+	}
+
+	return 0, errors.New("content length not found")
+}
+
 func DecodeMessage(message []byte) (string, []byte, error) {
 	header, content, found := bytes.Cut(message, []byte{'\r', '\n', '\r', '\n'})
 	if !found {
 		return "", nil, errors.New("header not found")
 	}
 
-	length, err := strconv.Atoi(string(header[len("Content-Length: "):]))
+	length, err := contentLength(header)
 	if err != nil {
 		return "", nil, err
 	}
 
+	if len(content) < length {
+		return "", nil, fmt.Errorf("content is %d bytes, header says %d", len(content), length)
+	}
+
 	var baseMessage BaseMessage
 	if err := json.Unmarshal(content[:length], &baseMessage); err != nil {
 		return "", nil, err
@@ -40,21 +81,81 @@ func DecodeMessage(message []byte) (string, []byte, error) {
 	return baseMessage.Method, content[:length], nil
 }
 
+// Split frames messages for a bufio.Scanner. A malformed header is skipped up
+// to the next Content-Length field instead of stopping the scanner, so one bad
+// message does not end the session. So is a length over MaxContentLength,
+// which would not fit the scanner's buffer, and text without any header.
 func Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return split(nil, data, atEOF)
+}
+
+// LoggingSplit is Split, logging what it skips to logger.
+func LoggingSplit(logger *log.Logger) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		return split(logger, data, atEOF)
+	}
+}
+
+// split frames the first message of data. The scanner reads more before it
+// calls again, even after a skip, so whatever follows a skipped header is
+// framed right away.
+func split(logger *log.Logger, data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for {
+		n, token, skipped := frame(logger, data[advance:], atEOF)
+		advance += n
+		if !skipped || advance == len(data) {
+			return advance, token, nil
+		}
+	}
+}
+
+// frame frames the first message of data, or says how much of data to skip.
+func frame(logger *log.Logger, data []byte, atEOF bool) (advance int, token []byte, skipped bool) {
 	header, content, found := bytes.Cut(data, []byte{'\r', '\n', '\r', '\n'})
 	if !found {
-		return 0, nil, nil
+		if atEOF {
+			return len(data), nil, false
+		}
+		if len(data) > maxHeaderLength {
+			advance := skipToHeader(data, len(data)-len("Content-Length"))
+			logf(logger, "Skipping %d bytes without a header", advance)
+			return advance, nil, true
+		}
+		return 0, nil, false
 	}
 
-	length, err := strconv.Atoi(string(header[len("Content-Length: "):]))
+	length, err := contentLength(header)
+	if err == nil && length > MaxContentLength {
+		err = fmt.Errorf("content length %d is over %d", length, MaxContentLength)
+	}
 	if err != nil {
-		return 0, nil, err
+		logf(logger, "Skipping header: %v", err)
+		return skipToHeader(data, len(header)+4), nil, true
 	}
 
 	if len(content) < length {
-		return 0, nil, nil
+		if atEOF {
+			return len(data), nil, false
+		}
+		return 0, nil, false
 	}
 
 	totalLength := len(header) + 4 + length
-	return totalLength, data[:totalLength], nil
+	return totalLength, data[:totalLength], false
+}
+
+// skipToHeader returns how much of data to skip to get to the next
+// Content-Length field after its start, or otherwise.
+func skipToHeader(data []byte, otherwise int) int {
+	if next := bytes.Index(data[1:], []byte("Content-Length")); next >= 0 {
+		return next + 1
+	}
+
+	return otherwise
+}
+
+func logf(logger *log.Logger, format string, args ...any) {
+	if logger != nil {
+		logger.Printf(format, args...)
+	}
 }
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func FuzzSplit(f *testing.F) {
+	f.Add([]byte(EncodeMessage(map[string]any{"jsonrpc": "2.0", "method": "initialized"})))
+	f.Add([]byte("Content-Length: 2\r\n\r\n{}Content-Length: 3\r\n\r\n{}"))
+	f.Add([]byte("Content-Length: -5\r\n\r\n{}"))
+	f.Add([]byte("\r\n\r\n"))
+	f.Add([]byte("Content-Length: 1099511627776\r\n\r\n{}"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Split(Split)
+		for scanner.Scan() {
+			DecodeMessage(scanner.Bytes())
+		}
+	})
+}
+
+func FuzzDecodeMessage(f *testing.F) {
+	f.Add([]byte(EncodeMessage(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize"})))
+	f.Add([]byte("Content-Length: 100\r\n\r\n{}"))
+	f.Add([]byte("Content-Type: x\r\n\r\n{}"))
+
+	f.Fuzz(func(t *testing.T, message []byte) {
+		DecodeMessage(message)
+	})
+}
+
+func TestSplitSkipsOversizedMessages(t *testing.T) {
+	message := EncodeMessage(map[string]any{"jsonrpc": "2.0", "method": "initialized"})
+	data := "Content-Length: 1099511627776\r\n\r\n{\"jsonrpc\":" +
+		strings.Repeat("x", 2*maxHeaderLength) + message
+
+	var logs bytes.Buffer
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 1024), 4*maxHeaderLength)
+	scanner.Split(LoggingSplit(log.New(&logs, "", 0)))
+
+	messages := []string{}
+	for scanner.Scan() {
+		messages = append(messages, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0] != message {
+		t.Errorf("messages = %q, want %q", messages, message)
+	}
+	if !strings.Contains(logs.String(), "content length 1099511627776 is over") {
+		t.Errorf("log %q does not mention the length", logs.String())
+	}
+}
@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	Received = "recv"
+	Sent     = "send"
+)
+
+// TraceEntry is one line of a trace file. Received entries carry the time it
+// took to handle the message, sent entries the time since the message that
+// produced them was received.
+type TraceEntry struct {
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"direction"`
+	Method    string          `json:"method,omitempty"`
+	Id        json.RawMessage `json:"id,omitempty"`
+	Duration  float64         `json:"durationMs"`
+	Message   json.RawMessage `json:"message"`
+	// Line is the line of the trace file the entry was read from.
+	Line int `json:"-"`
+}
+
+// Recorder writes every message going through the server as JSON Lines.
+// Messages sent while a received message is being handled are held back and
+// written after it, so each received entry is followed by its replies.
+type Recorder struct {
+	mu       sync.Mutex
+	writer   io.Writer
+	started  time.Time
+	handling bool
+	pending  []TraceEntry
+}
+
+func NewRecorder(writer io.Writer) *Recorder {
+	return &Recorder{
+		writer:  writer,
+		pending: []TraceEntry{},
+	}
+}
+
+func (recorder *Recorder) Begin() {
+	if recorder == nil {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.started = time.Now()
+	recorder.handling = true
+}
+
+func (recorder *Recorder) Received(content []byte) error {
+	if recorder == nil {
+		return nil
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	entry := newTraceEntry(Received, content, recorder.started)
+	entry.Time = recorder.started
+	recorder.handling = false
+
+	if err := recorder.write(entry); err != nil {
+		return err
+	}
+
+	for _, pending := range recorder.pending {
+		if err := recorder.write(pending); err != nil {
+			return err
+		}
+	}
+	recorder.pending = recorder.pending[:0]
+
+	return nil
+}
+
+func (recorder *Recorder) Sent(content []byte) error {
+	if recorder == nil {
+		return nil
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if !recorder.handling {
+		return recorder.write(newTraceEntry(Sent, content, time.Now()))
+	}
+
+	recorder.pending = append(recorder.pending, newTraceEntry(Sent, content, recorder.started))
+	return nil
+}
+
+func (recorder *Recorder) write(entry TraceEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = recorder.writer.Write(append(line, '\n'))
+	return err
+}
+
+func newTraceEntry(direction string, content []byte, since time.Time) TraceEntry {
+	var message struct {
+		Method string          `json:"method"`
+		Id     json.RawMessage `json:"id"`
+	}
+	json.Unmarshal(content, &message)
+
+	now := time.Now()
+	return TraceEntry{
+		Time:      now,
+		Direction: direction,
+		Method:    message.Method,
+		Id:        message.Id,
+		Duration:  float64(now.Sub(since).Microseconds()) / 1000,
+		Message:   json.RawMessage(bytes.Clone(content)),
+	}
+}
+
+func ReadTrace(reader io.Reader) ([]TraceEntry, error) {
+	entries := []TraceEntry{}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		entry := TraceEntry{Line: number}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
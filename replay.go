@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/neet-007/lox_lsp_first/pkg/analysis"
+	"github.com/neet-007/lox_lsp_first/pkg/rpc"
+)
+
+// ReplayMismatch describes a received message whose replies differ from the
+// ones in the transcript. Expected and Actual hold the normalized messages.
+type ReplayMismatch struct {
+	Line     int
+	Method   string
+	Expected []string
+	Actual   []string
+}
+
+func (mismatch ReplayMismatch) String() string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "line %d: replies to '%s' differ\n", mismatch.Line, mismatch.Method)
+	builder.WriteString("  expected:\n")
+	for _, message := range mismatch.Expected {
+		fmt.Fprintf(&builder, "    %s\n", message)
+	}
+	builder.WriteString("  actual:\n")
+	for _, message := range mismatch.Actual {
+		fmt.Fprintf(&builder, "    %s\n", message)
+	}
+
+	return builder.String()
+}
+
+// Replay feeds every received message of a transcript through
+// safeHandleMessage on a fresh server and compares what it sends with the
+// recorded replies, so a message the server panics on is a mismatch and the
+// replay goes on. The server does not index the workspace the transcript
+// names, which is not there to read on the machine replaying it.
+func Replay(logger *log.Logger, entries []rpc.TraceEntry) ([]ReplayMismatch, error) {
+	mismatches := []ReplayMismatch{}
+
+	analyser := analysis.NewAnaylser()
+	analyser.SkipIndex()
+	output := &bytes.Buffer{}
+	writer := NewTracer(output, nil)
+
+	expectedIds := newIdNormalizer()
+	actualIds := newIdNormalizer()
+
+	for i := 0; i < len(entries); i++ {
+		line := entryLine(entries, i)
+		entry := entries[i]
+		if entry.Direction != rpc.Received {
+			continue
+		}
+
+		expected := []string{}
+		for i+1 < len(entries) && entries[i+1].Direction == rpc.Sent {
+			i++
+			message, err := normalizeMessage(entries[i].Message, expectedIds)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", entryLine(entries, i), err)
+			}
+			expected = append(expected, message)
+		}
+
+		output.Reset()
+		writer.Begin(entry.Method, entry.Message)
+		safeHandleMessage(logger, writer, analyser, entry.Method, entry.Message)
+		writer.End(entry.Message)
+
+		actual := []string{}
+		for _, content := range splitMessages(output) {
+			message, err := normalizeMessage(content, actualIds)
+			if err != nil {
+				return nil, err
+			}
+			actual = append(actual, message)
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			mismatches = append(mismatches, ReplayMismatch{
+				Line:     line,
+				Method:   entry.Method,
+				Expected: expected,
+				Actual:   actual,
+			})
+		}
+	}
+
+	return mismatches, nil
+}
+
+// entryLine returns the line of the trace file that entries[i] was read
+// from, or its position when it was not read from one.
+func entryLine(entries []rpc.TraceEntry, i int) int {
+	if entries[i].Line > 0 {
+		return entries[i].Line
+	}
+
+	return i + 1
+}
+
+func ReplayFile(logger *log.Logger, reader io.Reader) ([]ReplayMismatch, error) {
+	entries, err := rpc.ReadTrace(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return Replay(logger, entries)
+}
+
+func splitMessages(output *bytes.Buffer) [][]byte {
+	messages := [][]byte{}
+
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	scanner.Split(rpc.Split)
+	for scanner.Scan() {
+		_, content, err := rpc.DecodeMessage(scanner.Bytes())
+		if err != nil {
+			continue
+		}
+		messages = append(messages, bytes.Clone(content))
+	}
+
+	return messages
+}
+
+// idNormalizer renames ids in the order they first appear, so a transcript
+// still matches when the client numbered its requests differently. The
+// number 1 and the string "1" are different ids.
+type idNormalizer struct {
+	ids map[idKey]int
+}
+
+type idKey struct {
+	kind  string
+	value string
+}
+
+func newIdNormalizer() *idNormalizer {
+	return &idNormalizer{
+		ids: map[idKey]int{},
+	}
+}
+
+func (normalizer *idNormalizer) normalize(id any) int {
+	key := idKey{kind: fmt.Sprintf("%T", id), value: fmt.Sprintf("%v", id)}
+	if n, ok := normalizer.ids[key]; ok {
+		return n
+	}
+
+	n := len(normalizer.ids) + 1
+	normalizer.ids[key] = n
+	return n
+}
+
+var durationPattern = regexp.MustCompile(`\d+ms`)
+
+func normalizeMessage(content []byte, ids *idNormalizer) (string, error) {
+	var message map[string]any
+	if err := json.Unmarshal(content, &message); err != nil {
+		return "", err
+	}
+
+	if id, ok := message["id"]; ok && id != nil {
+		message["id"] = ids.normalize(id)
+	}
+
+	if message["method"] == "$/logTrace" {
+		if params, ok := message["params"].(map[string]any); ok {
+			if text, ok := params["message"].(string); ok {
+				params["message"] = durationPattern.ReplaceAllString(text, "0ms")
+			}
+		}
+	}
+
+	normalized, err := json.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+
+	return string(normalized), nil
+}
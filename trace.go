@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/neet-007/lox_lsp_first/internal/lsp"
+	"github.com/neet-007/lox_lsp_first/pkg/rpc"
+)
+
+// Tracer is the writer every outgoing message goes through. It mirrors the
+// traffic into the trace file and, when the client asked for it with
+// $/setTrace or the initialize trace option, into $/logTrace notifications.
+type Tracer struct {
+	writer   io.Writer
+	recorder *rpc.Recorder
+	level    string
+	method   string
+	started  time.Time
+}
+
+type traceMessage struct {
+	Method string          `json:"method"`
+	Id     json.RawMessage `json:"id"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+func NewTracer(writer io.Writer, recorder *rpc.Recorder) *Tracer {
+	return &Tracer{
+		writer:   writer,
+		recorder: recorder,
+		level:    lsp.TraceOff,
+	}
+}
+
+func (tracer *Tracer) SetLevel(level string) {
+	switch level {
+	case lsp.TraceMessages, lsp.TraceVerbose:
+		tracer.level = level
+	default:
+		tracer.level = lsp.TraceOff
+	}
+}
+
+func (tracer *Tracer) Begin(method string, content []byte) {
+	tracer.method = method
+	tracer.started = time.Now()
+	tracer.recorder.Begin()
+
+	if tracer.level == lsp.TraceOff {
+		return
+	}
+
+	var message traceMessage
+	json.Unmarshal(content, &message)
+
+	if message.Id != nil {
+		tracer.logTrace(fmt.Sprintf("Received request '%s - (%s)'.", method, message.Id),
+			"Params: ", message.Params)
+		return
+	}
+
+	tracer.logTrace(fmt.Sprintf("Received notification '%s'.", method),
+		"Params: ", message.Params)
+}
+
+func (tracer *Tracer) End(content []byte) error {
+	return tracer.recorder.Received(content)
+}
+
+func (tracer *Tracer) Write(data []byte) (int, error) {
+	n, err := tracer.writer.Write(data)
+	if err != nil {
+		return n, err
+	}
+
+	_, content, decodeErr := rpc.DecodeMessage(data)
+	if decodeErr != nil {
+		return n, nil
+	}
+
+	if err := tracer.recorder.Sent(content); err != nil {
+		return n, err
+	}
+
+	if tracer.level == lsp.TraceOff {
+		return n, nil
+	}
+
+	var message traceMessage
+	json.Unmarshal(content, &message)
+
+	if message.Method != "" {
+		tracer.logTrace(fmt.Sprintf("Sending notification '%s'.", message.Method),
+			"Params: ", message.Params)
+		return n, nil
+	}
+
+	took := time.Since(tracer.started).Milliseconds()
+	if message.Error != nil {
+		tracer.logTrace(fmt.Sprintf("Sending response '%s - (%s)'. Processing request failed after %dms",
+			tracer.method, message.Id, took), "Error: ", message.Error)
+		return n, nil
+	}
+
+	tracer.logTrace(fmt.Sprintf("Sending response '%s - (%s)'. Processing request took %dms",
+		tracer.method, message.Id, took), "Result: ", message.Result)
+	return n, nil
+}
+
+func (tracer *Tracer) logTrace(message string, label string, payload json.RawMessage) {
+	verbose := ""
+	if tracer.level == lsp.TraceVerbose && payload != nil {
+		compact := &bytes.Buffer{}
+		if err := json.Compact(compact, payload); err == nil {
+			verbose = label + compact.String()
+		}
+	}
+
+	reply := []byte(rpc.EncodeMessage(lsp.NewLogTraceNotification(message, verbose)))
+	if _, err := tracer.writer.Write(reply); err != nil {
+		return
+	}
+
+	if _, content, err := rpc.DecodeMessage(reply); err == nil {
+		tracer.recorder.Sent(content)
+	}
+}